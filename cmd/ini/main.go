@@ -0,0 +1,712 @@
+// Command ini queries and edits INI files.
+//
+// Usage:
+//
+//	ini [flags] COMMAND [ARGS...]
+//
+// Flags may precede the command or follow it and its arguments. An argument that begins with "-"
+// but is not a flag, such as the "-1" in "set KEY -1", is passed to the command, and every argument
+// following "--" is passed to the command even if it is a flag.
+//
+// Commands that read an INI file read it from the file given by -f or, if -f is not set, standard
+// input. Commands that change the file write it back in place if -f is set and to standard output
+// otherwise. Comments and formatting are preserved for keys that are not changed.
+//
+// Commands:
+//
+//	get KEY          Print the first value of KEY.
+//	get-all KEY      Print every value of KEY, one per line.
+//	set KEY VALUE    Set KEY to VALUE, replacing any existing values.
+//	unset KEY        Remove every value of KEY.
+//	sections         Print the name of each section.
+//	keys [SECTION]   Print each key, or each key in SECTION.
+//	fmt              Reformat the file in a canonical style.
+//	lint             Check the file for errors and likely mistakes.
+//	to-json          Print the file's keys and values as JSON, with a nested object per section.
+//	from-json        Read a JSON object from standard input and print it as INI.
+//	diff A B         Print the differences between the values of files A and B.
+//	merge [A] B...   Print A with the values of each following file set in it. If -f is set, A
+//	                 is omitted and the values of every file are set in FILE instead.
+//	encrypt KEY      Encrypt the values of KEY with the key in --key-file.
+//	decrypt KEY      Print the first value of KEY, decrypted with the key in --key-file.
+//	keygen FILE      Write a new random key to FILE, which must not exist.
+//	query PATTERN    Print each key matching PATTERN (e.g., "remote.*.url") and its values.
+//
+// Flags:
+//
+//	-f FILE          Read FILE, and write changes back to it.
+//	--separator SEP  Separator between section names and keys (default ".").
+//	--casing CASE    Casing of unquoted keys: lower, upper, or sensitive (default "sensitive").
+//	--dialect NAME   Set of Reader options to use: default, php, heredoc, dotenv, or properties
+//	                 (default "default").
+//	--indent STRING  For fmt, indent keys in sections with STRING.
+//	--sort           For fmt, sort sections and keys.
+//	--comment CHAR   For fmt, begin comments with CHAR, either ; or #.
+//	--rules RULES    For lint, the comma-separated rules to check (default "all").
+//	--types          For to-json, write numbers and booleans as JSON numbers and booleans.
+//	--key-file FILE  For encrypt and decrypt, read the encryption key from FILE.
+//	--captures       For query, print the text matched by each wildcard before each key.
+//	--redact         For get, get-all, query, diff, and to-json, print secret values as
+//	                 [redacted]. Other commands print and write values as they are.
+//
+// Commands exit with status 1 if a key is not found or, for diff and lint, if there are
+// differences or errors. Other errors exit with status 2.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// errFailed is returned by commands that fail without an error message, such as get for a missing
+// key. It exits with status 1.
+var errFailed = errors.New("failed")
+
+// cli is the state of a single run of the command.
+type cli struct {
+	dec      ini.Reader
+	style    ini.FormatStyle
+	rules    ini.LintRule
+	types    bool
+	file     string
+	keyFile  string
+	captures bool
+	redact   bool
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+// command is a subcommand. min and max are the minimum and maximum number of arguments, where a max
+// of -1 means there is no maximum.
+type command struct {
+	min, max int
+	usage    string
+	run      func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"get":       {1, 1, "get KEY", (*cli).get},
+	"get-all":   {1, 1, "get-all KEY", (*cli).getAll},
+	"set":       {2, 2, "set KEY VALUE", (*cli).set},
+	"unset":     {1, 1, "unset KEY", (*cli).unset},
+	"sections":  {0, 0, "sections", (*cli).sections},
+	"keys":      {0, 1, "keys [SECTION]", (*cli).keys},
+	"fmt":       {0, 0, "fmt", (*cli).fmt},
+	"lint":      {0, 0, "lint", (*cli).lint},
+	"to-json":   {0, 0, "to-json", (*cli).toJSON},
+	"from-json": {0, 0, "from-json", (*cli).fromJSON},
+	"diff":      {2, 2, "diff A B", (*cli).diff},
+	"merge":     {1, -1, "merge [A] B...", (*cli).merge},
+	"encrypt":   {1, 1, "encrypt KEY", (*cli).encrypt},
+	"decrypt":   {1, 1, "decrypt KEY", (*cli).decrypt},
+	"keygen":    {1, 1, "keygen FILE", (*cli).keygen},
+	"query":     {1, 1, "query PATTERN", (*cli).query},
+}
+
+// dialects are the Reader options selected by --dialect.
+var dialects = map[string]ini.Reader{
+	"default":    {},
+	"php":        {ArrayKeys: true},
+	"heredoc":    {Heredoc: true},
+	"dotenv":     {DotEnv: true},
+	"properties": {Properties: true},
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("ini", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(stderr) }
+	fs.StringVar(&c.file, "f", "", "read `FILE` and write changes back to it")
+	separator := fs.String("separator", ".", "separator between section names and keys")
+	casing := fs.String("casing", "sensitive", "casing of unquoted keys: lower, upper, or sensitive")
+	dialect := fs.String("dialect", "default", "dialect of INI: default, php, heredoc, dotenv, or properties")
+	c.style = ini.DefaultStyle
+	fs.StringVar(&c.style.Indent, "indent", "", "fmt: indent keys in sections with `STRING`")
+	sortKeys := fs.Bool("sort", false, "fmt: sort sections and keys")
+	comment := fs.String("comment", "", "fmt: begin comments with `CHAR` (; or #)")
+	rules := fs.String("rules", "all", "lint: comma-separated `RULES` to check")
+	fs.BoolVar(&c.types, "types", false, "to-json: write numbers and booleans as JSON numbers and booleans")
+	fs.StringVar(&c.keyFile, "key-file", "", "encrypt, decrypt: read the encryption key from `FILE`")
+	fs.BoolVar(&c.captures, "captures", false, "query: print the text matched by each wildcard")
+	fs.BoolVar(&c.redact, "redact", false, "get, get-all, query, diff, to-json: print secret values as [redacted]")
+
+	// Allow flags to follow the command and its arguments. After the command, only defined flags
+	// are parsed as flags, so that "set k -1" sets k to -1, and "--" ends the flags.
+	var pos []string
+	for len(args) > 0 {
+		if len(pos) > 0 && !isFlag(fs, args[0]) {
+			pos, args = append(pos, args[0]), args[1:]
+			continue
+		}
+		if err := fs.Parse(args); err == flag.ErrHelp {
+			return 0
+		} else if err != nil {
+			return 2
+		}
+		rest := fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			pos = append(pos, rest...)
+			break
+		} else if len(rest) == 0 {
+			break
+		}
+		pos, args = append(pos, rest[0]), rest[1:]
+	}
+
+	if len(pos) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	dec, ok := dialects[*dialect]
+	if !ok {
+		fmt.Fprintf(stderr, "ini: unknown dialect %q\n", *dialect)
+		return 2
+	}
+	switch *casing {
+	case "lower":
+		dec.Casing = ini.LowerCase
+	case "upper":
+		dec.Casing = ini.UpperCase
+	case "sensitive":
+		dec.Casing = ini.CaseSensitive
+	default:
+		fmt.Fprintf(stderr, "ini: unknown casing %q\n", *casing)
+		return 2
+	}
+	dec.Separator = *separator
+	dec.True = ini.True
+	c.dec = dec
+
+	switch *comment {
+	case "":
+	case ";", "#":
+		c.style.Comment = (*comment)[0]
+	default:
+		fmt.Fprintf(stderr, "ini: invalid comment character %q\n", *comment)
+		return 2
+	}
+	c.style.SortKeys, c.style.SortSections = *sortKeys, *sortKeys
+	c.style.Reader = &c.dec
+
+	var err error
+	if c.rules, err = ini.ParseLintRules(*rules); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	name, args := pos[0], pos[1:]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "ini: unknown command %q\n", name)
+		return 2
+	}
+	if len(args) < cmd.min || cmd.max >= 0 && len(args) > cmd.max {
+		fmt.Fprintf(stderr, "usage: ini [flags] %s\n", cmd.usage)
+		return 2
+	}
+
+	switch err := cmd.run(c, args); err {
+	case nil:
+		return 0
+	case errFailed:
+		return 1
+	default:
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+}
+
+// wrappedError is an error from the ini package given the context of a command, such as the key
+// that could not be set. Its message begins with "ini: " only once.
+type wrappedError struct {
+	context string
+	err     error
+}
+
+// wrapError returns err with the context given by format and args.
+func wrapError(err error, format string, args ...interface{}) error {
+	return &wrappedError{context: fmt.Sprintf(format, args...), err: err}
+}
+
+func (e *wrappedError) Error() string {
+	return "ini: " + e.context + ": " + strings.TrimPrefix(e.err.Error(), "ini: ")
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
+// isFlag returns whether arg is "--" or names a flag defined in fs, as in "-f" or "--separator=/".
+func isFlag(fs *flag.FlagSet, arg string) bool {
+	if arg == "--" {
+		return true
+	}
+	name, ok := strings.CutPrefix(arg, "-")
+	if !ok {
+		return false
+	}
+	name = strings.TrimPrefix(name, "-")
+	name, _, _ = strings.Cut(name, "=")
+	return fs.Lookup(name) != nil
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "usage: ini [flags] COMMAND [ARGS...]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fmt.Fprintln(w, "  -f FILE          read FILE and write changes back to it")
+	fmt.Fprintln(w, "  --separator SEP  separator between section names and keys (default \".\")")
+	fmt.Fprintln(w, "  --casing CASE    casing of unquoted keys: lower, upper, or sensitive")
+	fmt.Fprintln(w, "  --dialect NAME   dialect of INI: default, php, heredoc, dotenv, or properties")
+	fmt.Fprintln(w, "  --indent STRING  fmt: indent keys in sections with STRING")
+	fmt.Fprintln(w, "  --sort           fmt: sort sections and keys")
+	fmt.Fprintln(w, "  --comment CHAR   fmt: begin comments with CHAR (; or #)")
+	fmt.Fprintln(w, "  --rules RULES    lint: comma-separated rules to check (default \"all\")")
+	fmt.Fprintln(w, "  --types          to-json: write numbers and booleans as JSON numbers and booleans")
+	fmt.Fprintln(w, "  --key-file FILE  encrypt, decrypt: read the encryption key from FILE")
+	fmt.Fprintln(w, "  --captures       query: print the text matched by each wildcard")
+	fmt.Fprintln(w, "  --redact         get, get-all, query, diff, to-json: print secret values as [redacted]")
+}
+
+// input returns the contents of the input file, or standard input if there is none, and its name.
+func (c *cli) input() (src []byte, name string, err error) {
+	if c.file == "" {
+		src, err = io.ReadAll(c.stdin)
+		return src, "<stdin>", err
+	}
+	src, err = os.ReadFile(c.file)
+	return src, c.file, err
+}
+
+// document parses the input as a Document.
+func (c *cli) document() (*ini.Document, error) {
+	src, name, err := c.input()
+	if err != nil {
+		return nil, err
+	}
+	return c.parse(name, src)
+}
+
+// parse parses src, read from the file name, as a Document.
+func (c *cli) parse(name string, src []byte) (*ini.Document, error) {
+	doc, err := c.dec.ParseDocument(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return doc, nil
+}
+
+// readFile parses the named file as a Document.
+func (c *cli) readFile(name string) (*ini.Document, error) {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.parse(name, src)
+}
+
+// output writes src to the input file, if there is one, or standard output.
+func (c *cli) output(src []byte) error {
+	if c.file == "" {
+		_, err := c.stdout.Write(src)
+		return err
+	}
+	return writeFile(c.file, src)
+}
+
+// writeFile replaces the contents of the file name with src. The file is replaced by renaming a
+// temporary file over it so that it is never left partially written.
+func writeFile(name string, src []byte) (err error) {
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(name); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// println prints each of lines on its own line.
+func (c *cli) println(lines []string) error {
+	var b bytes.Buffer
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	_, err := c.stdout.Write(b.Bytes())
+	return err
+}
+
+// value returns value for printing, redacted if --redact is set and value is secret.
+func (c *cli) value(key, value string) string {
+	if c.redact {
+		return ini.Redact(key, value)
+	}
+	return value
+}
+
+func (c *cli) get(args []string) error {
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+	value, ok := doc.Get(args[0])
+	if !ok {
+		return errFailed
+	}
+	return c.println([]string{c.value(args[0], value)})
+}
+
+func (c *cli) getAll(args []string) error {
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+	values := doc.GetAll(args[0])
+	if len(values) == 0 {
+		return errFailed
+	}
+	for i, v := range values {
+		values[i] = c.value(args[0], v)
+	}
+	return c.println(values)
+}
+
+func (c *cli) set(args []string) error {
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+	if err := doc.Set(args[0], args[1]); err != nil {
+		return wrapError(err, "cannot set %q", args[0])
+	}
+	return c.output(doc.Bytes())
+}
+
+func (c *cli) unset(args []string) error {
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+	if found, err := doc.Unset(args[0]); err != nil {
+		return err
+	} else if !found {
+		return errFailed
+	}
+	return c.output(doc.Bytes())
+}
+
+func (c *cli) sections([]string) error {
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+	return c.println(doc.Sections())
+}
+
+func (c *cli) keys(args []string) error {
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+
+	keys := doc.Keys()
+	if len(args) > 0 {
+		prefix := args[0] + c.dec.KeySeparator()
+		n := 0
+		for _, k := range keys {
+			if strings.HasPrefix(k, prefix) {
+				keys[n] = k
+				n++
+			}
+		}
+		keys = keys[:n]
+	}
+	return c.println(keys)
+}
+
+func (c *cli) fmt([]string) error {
+	src, name, err := c.input()
+	if err != nil {
+		return err
+	}
+	out, err := ini.Format(src, c.style)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return c.output(out)
+}
+
+func (c *cli) lint([]string) error {
+	src, name, err := c.input()
+	if err != nil {
+		return err
+	}
+	doc, err := c.dec.ParseDocument(src)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "%s: %v\n", name, err)
+		return errFailed
+	}
+
+	warnings := ini.Lint(doc, c.rules)
+	if len(warnings) == 0 {
+		return nil
+	}
+	lines := make([]string, len(warnings))
+	for i, w := range warnings {
+		lines[i] = name + ":" + w.String()
+	}
+	if err := c.println(lines); err != nil {
+		return err
+	}
+	return errFailed
+}
+
+func (c *cli) toJSON([]string) error {
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+	v := doc.Values()
+	if c.redact {
+		v = new(ini.Redactor).Values(v)
+	}
+	out, err := ini.ToJSON(v, c.jsonOptions())
+	if err != nil {
+		return err
+	}
+	return c.println([]string{string(out)})
+}
+
+func (c *cli) fromJSON([]string) error {
+	in, err := io.ReadAll(c.stdin)
+	if err != nil {
+		return err
+	}
+	v, err := ini.FromJSON(in, c.jsonOptions())
+	if err != nil {
+		return wrapError(err, "reading JSON")
+	}
+	doc, err := c.dec.NewDocument(v)
+	if err != nil {
+		return err
+	}
+	_, err = doc.WriteTo(c.stdout)
+	return err
+}
+
+// jsonOptions returns the options for converting to and from JSON.
+func (c *cli) jsonOptions() ini.JSONOptions {
+	return ini.JSONOptions{Separator: c.dec.Separator, InferTypes: c.types, Indent: "  "}
+}
+
+func (c *cli) diff(args []string) error {
+	a, err := c.readFile(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := c.readFile(args[1])
+	if err != nil {
+		return err
+	}
+
+	av, bv := a.Values(), b.Values()
+	keys := make([]string, 0, len(av)+len(bv))
+	for k := range av {
+		keys = append(keys, k)
+	}
+	for k := range bv {
+		if _, ok := av[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, k := range keys {
+		if slices.Equal(av[k], bv[k]) {
+			continue
+		}
+		for _, v := range av[k] {
+			lines = append(lines, "- "+k+" = "+c.value(k, v))
+		}
+		for _, v := range bv[k] {
+			lines = append(lines, "+ "+k+" = "+c.value(k, v))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if err := c.println(lines); err != nil {
+		return err
+	}
+	return errFailed
+}
+
+func (c *cli) merge(args []string) error {
+	var (
+		doc *ini.Document
+		err error
+	)
+	if c.file != "" {
+		doc, err = c.document()
+	} else {
+		doc, err = c.readFile(args[0])
+		args = args[1:]
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, name := range args {
+		src, err := c.readFile(name)
+		if err != nil {
+			return err
+		}
+		for _, k := range src.Keys() {
+			values := src.GetAll(k)
+			if err := doc.Set(k, values[0]); err != nil {
+				return wrapError(err, "cannot set %q", k)
+			}
+			for _, v := range values[1:] {
+				if err := doc.Add(k, v); err != nil {
+					return wrapError(err, "cannot set %q", k)
+				}
+			}
+		}
+	}
+
+	return c.output(doc.Bytes())
+}
+
+// cipher returns the Cipher for the key in --key-file.
+func (c *cli) cipher() (*ini.Cipher, error) {
+	if c.keyFile == "" {
+		return nil, errors.New("ini: --key-file is required")
+	}
+	return ini.LoadKeyFile(c.keyFile)
+}
+
+func (c *cli) encrypt(args []string) error {
+	ciph, err := c.cipher()
+	if err != nil {
+		return err
+	}
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+	if _, ok := doc.Get(args[0]); !ok {
+		return errFailed
+	}
+	if err := doc.Encrypt(args[0], ciph); err != nil {
+		return wrapError(err, "cannot encrypt %q", args[0])
+	}
+	return c.output(doc.Bytes())
+}
+
+func (c *cli) decrypt(args []string) error {
+	ciph, err := c.cipher()
+	if err != nil {
+		return err
+	}
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+	value, ok := doc.Get(args[0])
+	if !ok {
+		return errFailed
+	}
+	if value, err = ciph.Decrypt(args[0], value); err != nil {
+		return wrapError(err, "cannot decrypt %q", args[0])
+	}
+	return c.println([]string{value})
+}
+
+func (c *cli) keygen(args []string) error {
+	key, err := ini.GenerateKey()
+	if err != nil {
+		return err
+	}
+	return ini.WriteKeyFile(args[0], key)
+}
+
+func (c *cli) query(args []string) error {
+	q, err := ini.CompileQuery(args[0], c.dec.Separator)
+	if err != nil {
+		return err
+	}
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+
+	matches := q.Select(doc.Values())
+	if len(matches) == 0 {
+		return errFailed
+	}
+	var lines []string
+	for _, m := range matches {
+		prefix := ""
+		if c.captures {
+			for _, capture := range m.Captures {
+				prefix += capture + "\t"
+			}
+		}
+		if len(m.Values) == 0 {
+			lines = append(lines, prefix+m.Key)
+		}
+		for _, v := range m.Values {
+			lines = append(lines, prefix+m.Key+" = "+c.value(m.Key, v))
+		}
+	}
+	return c.println(lines)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type runResult struct {
+	code           int
+	stdout, stderr string
+}
+
+func testRun(t *testing.T, stdin string, args ...string) runResult {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return runResult{code, stdout.String(), stderr.String()}
+}
+
+func writeTemp(t *testing.T, name, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	const src = "; settings\n[server]\nhost = localhost ; local\nport = 80\nport = 8080\n\n[client]\nretry\n"
+	cases := []struct {
+		name  string
+		stdin string
+		args  []string
+		want  runResult
+	}{
+		{"get", src, []string{"get", "server.host"}, runResult{0, "localhost\n", ""}},
+		{"get flag", src, []string{"get", "client.retry"}, runResult{0, "1\n", ""}},
+		{"get missing", src, []string{"get", "server.missing"}, runResult{1, "", ""}},
+		{"get-all", src, []string{"get-all", "server.port"}, runResult{0, "80\n8080\n", ""}},
+		{"sections", src, []string{"sections"}, runResult{0, "server\nclient\n", ""}},
+		{"keys", src, []string{"keys"}, runResult{0, "server.host\nserver.port\nclient.retry\n", ""}},
+		{"keys section", src, []string{"keys", "server"}, runResult{0, "server.host\nserver.port\n", ""}},
+		{"set", src, []string{"set", "server.port", "443"}, runResult{0,
+			"; settings\n[server]\nhost = localhost ; local\nport = 443\n\n[client]\nretry\n", ""}},
+		{"set negative", "a = 1\n", []string{"set", "a", "-1"}, runResult{0, "a = -1\n", ""}},
+		{"set after --", "a = 1\n", []string{"--", "set", "a", "--sort"}, runResult{0, "a = --sort\n", ""}},
+		{"set new", "a = 1\n", []string{"set", "b.c", "x y"}, runResult{0, "a = 1\n\n[b]\nc = x y\n", ""}},
+		{"unset", src, []string{"unset", "server.host"}, runResult{0,
+			"; settings\n[server]\nport = 80\nport = 8080\n\n[client]\nretry\n", ""}},
+		{"unset missing", src, []string{"unset", "x"}, runResult{1, "", ""}},
+		{"fmt", "; c\n[s]\nk   =   1   ;x\n\n\n[t]\n  a=2", []string{"fmt"}, runResult{0,
+			"; c\n[s]\nk = 1 ;x\n\n[t]\na = 2\n", ""}},
+		{"fmt style", "[b]\nk=1 # c\n[a]\nj=2\n", []string{"fmt", "--sort", "--indent", "  ", "--comment", ";"}, runResult{0,
+			"[a]\n  j = 2\n\n[b]\n  k = 1 ; c\n", ""}},
+		{"lint", "; ok\n[s]\nk = 1\n", []string{"lint"}, runResult{0, "", ""}},
+		{"lint error", "k = 1\n= 2\n", []string{"lint"}, runResult{1, "",
+			"<stdin>: ini: syntax error at 2:1: ini: key is empty -- keys may not be blank\n"}},
+		{"lint warnings", "[s]\nk = 1\nk = 2\ne =\n", []string{"lint"}, runResult{1,
+			"<stdin>:3:1: key \"s.k\" overrides the value set on line 2 (overridden-keys)\n" +
+				"<stdin>:4:1: key \"s.e\" has an empty value (empty-values)\n", ""}},
+		{"lint rules", "[s]\nk = 1\nk = 2\ne =\n", []string{"lint", "--rules", "empty-values"}, runResult{1,
+			"<stdin>:4:1: key \"s.e\" has an empty value (empty-values)\n", ""}},
+		{"to-json", "b = 2\na = 1\na = x\n", []string{"to-json"}, runResult{0,
+			"{\n  \"a\": [\n    \"1\",\n    \"x\"\n  ],\n  \"b\": \"2\"\n}\n", ""}},
+		{"to-json types", "[s]\nn = 2\nb = true\n", []string{"to-json", "--types"}, runResult{0,
+			"{\n  \"s\": {\n    \"b\": true,\n    \"n\": 2\n  }\n}\n", ""}},
+		{"from-json", `{"s.b": [1, true], "a": "x;y", "n": null}`, []string{"from-json"}, runResult{0,
+			"a = \"x;y\"\n\n[s]\nb = 1\nb = true\n", ""}},
+		{"casing", "[S]\nK = v\n", []string{"--casing", "lower", "get", "s.k"}, runResult{0, "v\n", ""}},
+		{"separator", "[s]\nk = v\n", []string{"get", "s/k", "--separator=/"}, runResult{0, "v\n", ""}},
+		{"dialect", "k[] = 1\nk[] = 2\n", []string{"--dialect", "php", "get-all", "k"}, runResult{0, "1\n2\n", ""}},
+		{"dialect dotenv", "export A=1\n", []string{"--dialect", "dotenv", "get", "A"}, runResult{0, "1\n", ""}},
+		{"dialect properties", "a:b\\\n c\n", []string{"--dialect", "properties", "get", "a"}, runResult{0, "bc\n", ""}},
+		{"set properties", "a=1\n", []string{"--dialect", "properties", "set", "a", " x;y"}, runResult{0, "a=\\ x;y\n", ""}},
+		{"set subscript", "k[] = 1\n", []string{"--dialect", "php", "set", "k[]", "x"}, runResult{2, "",
+			"ini: cannot set \"k[]\": key cannot be written to document: \"k[]\" has a subscript, which ArrayKeys reads as key \"k\"\n"}},
+		{"set error", "a = 1\n", []string{"set", "", "x"}, runResult{2, "",
+			"ini: cannot set \"\": key cannot be written to document: ini: syntax error at 2:2: ini: key is empty -- keys may not be blank\n"}},
+		{"query", src, []string{"query", "*.port"}, runResult{0, "server.port = 80\nserver.port = 8080\n", ""}},
+		{"query captures", src, []string{"query", "--captures", "{server,client}.*"}, runResult{0,
+			"client\tretry\tclient.retry = 1\nserver\thost\tserver.host = localhost\n" +
+				"server\tport\tserver.port = 80\nserver\tport\tserver.port = 8080\n", ""}},
+		{"query missing", src, []string{"query", "**.missing"}, runResult{1, "", ""}},
+		{"query invalid", src, []string{"query", "server.{"}, runResult{2, "",
+			"ini: invalid query \"server.{\": unclosed {\n"}},
+		{"redact", "[db]\npassword = hunter2\nhost = h\n", []string{"query", "--redact", "db.*"}, runResult{0,
+			"db.host = h\ndb.password = [redacted]\n", ""}},
+		{"redact get", "password = hunter2\n", []string{"get", "password", "--redact"}, runResult{0, "[redacted]\n", ""}},
+		{"redact to-json", "password = hunter2\n", []string{"--redact", "to-json"}, runResult{0,
+			"{\n  \"password\": \"[redacted]\"\n}\n", ""}},
+		{"unknown command", "", []string{"nope"}, runResult{2, "", "ini: unknown command \"nope\"\n"}},
+		{"bad args", "", []string{"get"}, runResult{2, "", "usage: ini [flags] get KEY\n"}},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got := testRun(t, c.stdin, c.args...)
+			if got != c.want {
+				t.Errorf("run(%q) =\n%#v\nwant\n%#v", c.args, got, c.want)
+			}
+		})
+	}
+}
+
+func TestRun_file(t *testing.T) {
+	path := writeTemp(t, "a.ini", "; keep me\n[s]\nk = 1 # and me\n")
+	if got := testRun(t, "", "-f", path, "set", "s.k", "2"); got != (runResult{}) {
+		t.Fatalf("set = %#v", got)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "; keep me\n[s]\nk = 2 # and me\n"; got != want {
+		t.Errorf("file = %q; want %q", got, want)
+	}
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v; want %v", fi.Mode().Perm(), os.FileMode(0o600))
+	}
+}
+
+func TestRun_diffMerge(t *testing.T) {
+	a := writeTemp(t, "a.ini", "; base\n[s]\nk = 1\nj = 2\n")
+	b := writeTemp(t, "b.ini", "[s]\nk = 3\n[t]\nx = y\n")
+
+	want := runResult{1, "- s.j = 2\n- s.k = 1\n+ s.k = 3\n+ t.x = y\n", ""}
+	if got := testRun(t, "", "diff", a, b); got != want {
+		t.Errorf("diff =\n%#v\nwant\n%#v", got, want)
+	}
+	if got := testRun(t, "", "diff", a, a); got != (runResult{}) {
+		t.Errorf("diff a a = %#v; want no output", got)
+	}
+
+	want = runResult{0, "; base\n[s]\nk = 3\nj = 2\n\n[t]\nx = y\n", ""}
+	if got := testRun(t, "", "merge", a, b); got != want {
+		t.Errorf("merge =\n%#v\nwant\n%#v", got, want)
+	}
+
+	if got := testRun(t, "", "-f", a, "merge", b); got != (runResult{}) {
+		t.Fatalf("merge -f = %#v", got)
+	}
+	out, err := os.ReadFile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "; base\n[s]\nk = 3\nj = 2\n\n[t]\nx = y\n"; got != want {
+		t.Errorf("merge -f file = %q; want %q", got, want)
+	}
+}
+
+func TestRun_encrypt(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "ini.key")
+	if got := testRun(t, "", "keygen", key); got != (runResult{}) {
+		t.Fatalf("keygen = %#v", got)
+	}
+	if got := testRun(t, "", "keygen", key); got.code != 2 {
+		t.Errorf("keygen over existing key = %#v; want status 2", got)
+	}
+
+	path := writeTemp(t, "a.ini", "[db]\nhost = localhost\npassword = hunter2 ; rotate\n")
+	if got := testRun(t, "", "-f", path, "--key-file", key, "encrypt", "db.password"); got != (runResult{}) {
+		t.Fatalf("encrypt = %#v", got)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(b)
+	if !strings.HasPrefix(src, "[db]\nhost = localhost\npassword = enc:v1:") || !strings.HasSuffix(src, " ; rotate\n") {
+		t.Errorf("file = %q; want only the value encrypted", src)
+	}
+
+	if got, want := testRun(t, "", "-f", path, "--key-file", key, "decrypt", "db.password"), (runResult{0, "hunter2\n", ""}); got != want {
+		t.Errorf("decrypt = %#v; want %#v", got, want)
+	}
+	if got := testRun(t, "", "-f", path, "--key-file", key, "encrypt", "db.missing"); got != (runResult{1, "", ""}) {
+		t.Errorf("encrypt missing = %#v; want status 1", got)
+	}
+	if got, want := testRun(t, "", "-f", path, "encrypt", "db.password"), (runResult{2, "", "ini: --key-file is required\n"}); got != want {
+		t.Errorf("encrypt without key = %#v; want %#v", got, want)
+	}
+}
@@ -0,0 +1,245 @@
+package ini
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Config holds a snapshot of Values that may be read and replaced concurrently. Readers call Load
+// to get the current snapshot without locking, while writers replace the snapshot as a whole with
+// Store or Update, such as when reloading a configuration file. Callers may watch keys and
+// sections to be notified when their values change.
+//
+// The zero value of a Config is an empty Config, ready to use. A Config must not be copied after
+// first use.
+type Config struct {
+	// Separator is the string between a section name and a key, used to find the keys in a
+	// watched section. If Separator is None, a section's keys are all keys beginning with its
+	// name, and if Separator is the empty string, it defaults to "." (period). WatchSection copies
+	// Separator, so changing it does not affect sections that are already watched.
+	Separator string
+
+	cur atomic.Pointer[Values]
+
+	mu       sync.Mutex // mu serializes changes and guards gen and watchers
+	gen      uint64     // gen counts changes to cur
+	watchers map[*watcher]struct{}
+}
+
+// watcher is a subscription to changes to a key or section of a Config.
+type watcher struct {
+	name    string
+	section bool
+	prefix  string // prefix is the prefix of keys in a watched section
+	key     func(key string, old, new []string)
+	sect    func(section string, old, new Values)
+
+	mu         sync.Mutex // mu guards the fields below
+	seen       Values     // seen is the snapshot last passed to the watcher
+	seenGen    uint64     // seenGen is the generation of seen
+	next       Values     // next is the latest snapshot to pass to the watcher
+	nextGen    uint64     // nextGen is the generation of next
+	delivering bool       // delivering is true while a goroutine is calling the watcher
+}
+
+// NewConfig allocates a new Config holding a copy of v.
+func NewConfig(v Values) *Config {
+	c := new(Config)
+	c.Store(v)
+	return c
+}
+
+// Load returns the current snapshot of the Config's values. The snapshot is shared by all callers
+// and must not be modified. If nothing has been stored in the Config, Load returns nil.
+func (c *Config) Load() Values {
+	if p := c.cur.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Store replaces the Config's values with a copy of v and notifies watchers of any keys and
+// sections that changed. Watchers are called after the new values are visible to Load, by the
+// goroutine that called Store unless another goroutine is already calling the same watcher, in
+// which case that goroutine calls it again once it returns.
+func (c *Config) Store(v Values) {
+	c.notify(c.change(func(Values) Values { return v }))
+}
+
+// Update replaces the Config's values with a copy of the result of calling fn with a copy of its
+// current values, and returns the new values. fn may modify and return the Values it receives.
+// Calls to Update and Store are serialized, so fn always receives the latest values. As with Store,
+// watchers of changed keys and sections are notified after the new values are visible to Load. fn
+// must not call Store or Update.
+func (c *Config) Update(fn func(Values) Values) Values {
+	gen, old, cur, watchers := c.change(func(v Values) Values { return fn(v.Copy(nil)) })
+	c.notify(gen, old, cur, watchers)
+	return cur
+}
+
+// change replaces the Config's values with a copy of the result of fn, which is called with the
+// current values. It returns the generation of the change, the old and new values, and the
+// watchers to notify of the change.
+func (c *Config) change(fn func(Values) Values) (gen uint64, old, cur Values, watchers []*watcher) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old = c.Load()
+	cur = fn(old).Copy(nil)
+	c.cur.Store(&cur)
+	c.gen++
+
+	watchers = make([]*watcher, 0, len(c.watchers))
+	for w := range c.watchers {
+		watchers = append(watchers, w)
+	}
+	return c.gen, old, cur, watchers
+}
+
+// notify passes cur, the values of generation gen, to the watchers of keys and sections that
+// differ between old and cur. Watchers are called without holding c.mu so that they may read or
+// change the Config.
+func (c *Config) notify(gen uint64, old, cur Values, watchers []*watcher) {
+	changed := changedKeys(old, cur)
+	if len(changed) == 0 {
+		return
+	}
+	for _, w := range watchers {
+		if w.affected(changed) {
+			w.offer(gen, cur)
+		}
+	}
+}
+
+// changedKeys returns the set of keys whose values differ between old and cur, including keys
+// defined in only one of them.
+func changedKeys(old, cur Values) map[string]struct{} {
+	changed := make(map[string]struct{})
+	for k, ov := range old {
+		if nv, ok := cur[k]; !ok || !slices.Equal(ov, nv) {
+			changed[k] = struct{}{}
+		}
+	}
+	for k := range cur {
+		if _, ok := old[k]; !ok {
+			changed[k] = struct{}{}
+		}
+	}
+	return changed
+}
+
+// affected returns whether any of the changed keys is watched by w.
+func (w *watcher) affected(changed map[string]struct{}) bool {
+	if !w.section {
+		_, ok := changed[w.name]
+		return ok
+	}
+	for k := range changed {
+		if strings.HasPrefix(k, w.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// offer passes cur, the values of generation gen, to w unless w has already been given newer
+// values. If another goroutine is calling w, offer leaves cur for it to pass on and returns.
+// Otherwise, it calls w until w has been given the latest values offered.
+func (w *watcher) offer(gen uint64, cur Values) {
+	w.mu.Lock()
+	if gen <= w.nextGen {
+		w.mu.Unlock()
+		return
+	}
+	w.next, w.nextGen = cur, gen
+	if w.delivering {
+		w.mu.Unlock()
+		return
+	}
+
+	w.delivering = true
+	for w.seenGen < w.nextGen {
+		old, cur := w.seen, w.next
+		w.seen, w.seenGen = w.next, w.nextGen
+		w.mu.Unlock()
+		w.call(old, cur)
+		w.mu.Lock()
+	}
+	w.delivering = false
+	w.mu.Unlock()
+}
+
+// call calls w's function if the values it watches differ between old and cur.
+func (w *watcher) call(old, cur Values) {
+	if !w.section {
+		ov, oldOK := old[w.name]
+		nv, newOK := cur[w.name]
+		if oldOK != newOK || !slices.Equal(ov, nv) {
+			w.key(w.name, ov, nv)
+		}
+		return
+	}
+
+	ov := old.WithPrefix(nil, w.prefix)
+	nv := cur.WithPrefix(nil, w.prefix)
+	if !sameValues(ov, nv) {
+		w.sect(w.name, ov, nv)
+	}
+}
+
+// sameValues returns whether a and b contain the same keys and values.
+func sameValues(a, b Values) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, av := range a {
+		bv, ok := b[k]
+		if !ok || !slices.Equal(av, bv) {
+			return false
+		}
+	}
+	return true
+}
+
+// watch adds w to the Config's watchers, starting from the current values, and returns a function
+// to remove it.
+func (c *Config) watch(w *watcher) (cancel func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watchers == nil {
+		c.watchers = make(map[*watcher]struct{})
+	}
+	w.seen, w.seenGen = c.Load(), c.gen
+	w.next, w.nextGen = w.seen, w.seenGen
+	c.watchers[w] = struct{}{}
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.watchers, w)
+	}
+}
+
+// Watch calls fn whenever the values of key change, including when key is added or removed. fn
+// receives the key's old and new values, either of which is nil if the key was not defined. fn
+// must not modify either slice. Watch returns a function that cancels the subscription.
+//
+// Calls to fn are serialized, and fn is never passed values older than those it was last passed.
+// If the Config is changed concurrently, fn may be called once for several changes, receiving the
+// values from before the first of them and after the last.
+func (c *Config) Watch(key string, fn func(key string, old, new []string)) (cancel func()) {
+	return c.watch(&watcher{name: key, key: fn})
+}
+
+// WatchSection calls fn whenever any key in section or its subsections changes. A key is in a
+// section if it begins with the section name followed by the Config's Separator. fn receives the
+// old and new keys and values of the section, which it may keep or modify. WatchSection returns a
+// function that cancels the subscription.
+//
+// As with Watch, calls to fn are serialized and are never passed values older than those last
+// passed.
+func (c *Config) WatchSection(section string, fn func(section string, old, new Values)) (cancel func()) {
+	prefix := section + separatorOrDefault(c.Separator)
+	return c.watch(&watcher{name: section, section: true, prefix: prefix, sect: fn})
+}
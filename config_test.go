@@ -0,0 +1,187 @@
+package ini
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	var c Config
+	if v := c.Load(); v != nil {
+		t.Fatalf("Load() = %v; want nil", v)
+	}
+
+	src := Values{"a": {"1"}}
+	c.Store(src)
+	src.Add("a", "2")
+	if got, want := c.Load(), (Values{"a": {"1"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %v; want %v", got, want)
+	}
+
+	prev := c.Load()
+	got := c.Update(func(v Values) Values {
+		v.Add("a", "2")
+		v.Set("b", "3")
+		return v
+	})
+	if want := (Values{"a": {"1", "2"}, "b": {"3"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() = %v; want %v", got, want)
+	} else if !reflect.DeepEqual(c.Load(), want) {
+		t.Errorf("Load() = %v; want %v", c.Load(), want)
+	}
+	if want := (Values{"a": {"1"}}); !reflect.DeepEqual(prev, want) {
+		t.Errorf("previous snapshot = %v; want %v", prev, want)
+	}
+}
+
+func TestConfig_Watch(t *testing.T) {
+	type change struct {
+		name     string
+		old, new interface{}
+	}
+
+	var changes []change
+	c := NewConfig(Values{"a": {"1"}, "s.x": {"1"}, "s.t.y": {"2"}, "t.z": {"3"}})
+	cancelKey := c.Watch("a", func(key string, old, new []string) {
+		changes = append(changes, change{key, old, new})
+	})
+	cancelSection := c.WatchSection("s", func(section string, old, new Values) {
+		changes = append(changes, change{section, old, new})
+	})
+
+	c.Update(func(v Values) Values {
+		v.Set("t.z", "4") // Not watched
+		return v
+	})
+	if len(changes) != 0 {
+		t.Fatalf("changes = %v; want none", changes)
+	}
+
+	c.Update(func(v Values) Values {
+		v.Del("a")
+		v.Set("s.t.y", "3")
+		return v
+	})
+	want := []change{
+		{"a", []string{"1"}, []string(nil)},
+		{"s", Values{"s.x": {"1"}, "s.t.y": {"2"}}, Values{"s.x": {"1"}, "s.t.y": {"3"}}},
+	}
+	if len(changes) == 2 && changes[0].name == "s" {
+		changes[0], changes[1] = changes[1], changes[0]
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v; want %v", changes, want)
+	}
+
+	cancelKey()
+	cancelSection()
+	changes = nil
+	c.Store(Values{"a": {"2"}})
+	if len(changes) != 0 {
+		t.Errorf("changes after cancel = %v; want none", changes)
+	}
+}
+
+func TestConfig_WatchNoSeparator(t *testing.T) {
+	c := NewConfig(Values{"sx": {"1"}, "t": {"2"}})
+	c.Separator = None
+
+	var got []Values
+	c.WatchSection("s", func(section string, old, new Values) {
+		got = append(got, new)
+	})
+	c.Store(Values{"sx": {"2"}, "t": {"2"}})
+	if want := []Values{{"sx": {"2"}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v; want %v", got, want)
+	}
+}
+
+func TestConfig_concurrent(t *testing.T) {
+	c := NewConfig(Values{"n": {""}})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Update(func(v Values) Values {
+					v.Set("n", v.Get("n")+"x")
+					return v
+				})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = c.Load().Get("n")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := len(c.Load().Get("n")); n != 800 {
+		t.Errorf("len(n) = %d; want 800", n)
+	}
+}
+
+func TestConfig_watchOrder(t *testing.T) {
+	c := NewConfig(Values{"n": {""}})
+
+	var (
+		calls, active int32
+		last          string
+		bad           []string
+	)
+	c.Watch("n", func(key string, old, new []string) {
+		if atomic.AddInt32(&active, 1) != 1 {
+			bad = append(bad, "concurrent call")
+		}
+		defer atomic.AddInt32(&active, -1)
+		atomic.AddInt32(&calls, 1)
+
+		if old[0] != last || len(new[0]) <= len(old[0]) {
+			bad = append(bad, fmt.Sprintf("%d -> %d after %d", len(old[0]), len(new[0]), len(last)))
+		}
+		last = new[0]
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Update(func(v Values) Values {
+					v.Set("n", v.Get("n")+"x")
+					return v
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(bad) > 0 {
+		t.Errorf("watcher called out of order: %v", bad)
+	}
+	if len(last) != 800 || calls == 0 {
+		t.Errorf("last value has length %d after %d calls; want 800", len(last), calls)
+	}
+}
+
+func TestConfig_watchReentrant(t *testing.T) {
+	c := NewConfig(Values{"a": {"1"}})
+	var got [][]string
+	c.Watch("a", func(key string, old, new []string) {
+		got = append(got, new)
+		if new[0] == "2" {
+			c.Store(Values{"a": {"3"}})
+		}
+	})
+	c.Store(Values{"a": {"2"}})
+	if want := [][]string{{"2"}, {"3"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package ini
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EncryptedPrefix is the prefix of values encrypted by a Cipher. It is followed by the base64
+// encoding of the nonce and ciphertext.
+const EncryptedPrefix = "enc:v1:"
+
+// ErrDecrypt is returned by a Cipher when a value cannot be decrypted, either because it is
+// malformed or because it was encrypted with a different key.
+var ErrDecrypt = errors.New("ini: cannot decrypt value")
+
+// Cipher encrypts and decrypts values with AES-GCM. Encrypted values have the form
+// "enc:v1:<base64>" and may be committed alongside the rest of a file while the key is kept
+// elsewhere.
+//
+// Each value is encrypted with its key as additional authenticated data, so a value decrypts only
+// under the key it was encrypted for and cannot be copied to another key.
+//
+// Cipher is a SecretResolver, so a Reader with a Cipher as its Resolver decrypts encrypted values
+// as it reads them and records them as described by Reader.Resolver:
+//
+//	c, err := ini.LoadKeyFile("config.key")
+//	if err != nil {
+//		return err
+//	}
+//	dec := ini.Reader{Resolver: c}
+//	values := make(ini.Values)
+//	err = dec.Read(r, values)
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher returns a Cipher for key, which must be 16, 24, or 32 bytes long to select AES-128,
+// AES-192, or AES-256.
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("ini: invalid key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// GenerateKey returns a new random 32-byte key for AES-256.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// LoadKeyFile returns a Cipher for the key in the named file. The file holds the base64 encoding of
+// the key, optionally followed by a newline, as written by WriteKeyFile.
+func LoadKeyFile(name string) (*Cipher, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b)))
+	if err != nil {
+		return nil, fmt.Errorf("ini: key file %s is not valid base64", name)
+	}
+	c, err := NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return c, nil
+}
+
+// WriteKeyFile writes key to a new file with the given name, readable only by its owner. It returns
+// an error if the file already exists, so that a key in use is never overwritten.
+func WriteKeyFile(name string, key []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Encrypt returns plaintext, the value of key, encrypted with a random nonce as a value with
+// EncryptedPrefix. key is the full key, including its section, as a Reader records it.
+func (c *Cipher) Encrypt(key, plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), []byte(key))
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of value, the value of key, which must have been returned by
+// Encrypt with the same key and encryption key.
+func (c *Cipher) Decrypt(key, value string) (string, error) {
+	enc, ok := strings.CutPrefix(value, EncryptedPrefix)
+	if !ok {
+		return "", fmt.Errorf("%w: missing %q prefix", ErrDecrypt, EncryptedPrefix)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid base64", ErrDecrypt)
+	}
+	n := c.aead.NonceSize()
+	if len(sealed) < n+c.aead.Overhead() {
+		return "", fmt.Errorf("%w: too short", ErrDecrypt)
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:n], sealed[n:], []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("%w: wrong key, value of another key, or corrupt value", ErrDecrypt)
+	}
+	return string(plaintext), nil
+}
+
+// ResolveSecret decrypts ref, the value of key, if ref has the prefix "enc:". Encrypted values of
+// versions other than v1 are an error.
+func (c *Cipher) ResolveSecret(key, ref string) (string, bool, error) {
+	if !strings.HasPrefix(ref, "enc:") {
+		return "", false, nil
+	} else if !strings.HasPrefix(ref, EncryptedPrefix) {
+		version, _, _ := strings.Cut(strings.TrimPrefix(ref, "enc:"), ":")
+		return "", true, fmt.Errorf("%w: unsupported version %q", ErrDecrypt, version)
+	}
+	secret, err := c.Decrypt(key, ref)
+	return secret, true, err
+}
+
+// Encrypt encrypts each value of key with c, replacing only the text of each value. Values that
+// are already encrypted are left as they are. Encrypt returns an error if key is not in the
+// Document, if key is written without a value, or if a value is encrypted with a version other
+// than v1. In that case, or if any value cannot be encrypted, the Document is left unchanged.
+func (doc *Document) Encrypt(key string, c *Cipher) error {
+	prev := *doc
+	found := false
+	for i := 0; i < len(doc.entries); i++ {
+		e := &doc.entries[i]
+		if e.key.Text != key {
+			continue
+		}
+		found = true
+
+		if !e.hasValue {
+			*doc = prev
+			return fmt.Errorf("ini: key %q has no value to encrypt", key)
+		}
+		value := doc.value(e)
+		if strings.HasPrefix(value, EncryptedPrefix) {
+			continue
+		} else if rest, ok := strings.CutPrefix(value, "enc:"); ok {
+			*doc = prev
+			version, _, _ := strings.Cut(rest, ":")
+			return fmt.Errorf("ini: value of %q is encrypted with unsupported version %q", key, version)
+		}
+		enc, err := c.Encrypt(key, value)
+		if err != nil {
+			*doc = prev
+			return err
+		}
+		// Entries are reparsed after each edit, but their order is unchanged
+		if err := doc.edit(doc.replaceValue(e, enc)); err != nil {
+			*doc = prev
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("ini: key %q is not in the document", key)
+	}
+	return nil
+}
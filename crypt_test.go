@@ -0,0 +1,205 @@
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testCipher(t *testing.T) *Cipher {
+	t.Helper()
+	c, err := NewCipher(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCipher(t *testing.T) {
+	c := testCipher(t)
+	enc, err := c.Encrypt("db.password", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(enc, EncryptedPrefix) {
+		t.Fatalf("Encrypt = %q; want prefix %q", enc, EncryptedPrefix)
+	}
+	if again, _ := c.Encrypt("db.password", "hunter2"); again == enc {
+		t.Errorf("Encrypt returned %q twice; want a random nonce", enc)
+	}
+	if got, err := c.Decrypt("db.password", enc); err != nil || got != "hunter2" {
+		t.Errorf("Decrypt = %q, %v; want %q", got, err, "hunter2")
+	}
+	if _, err := c.Decrypt("db.other", enc); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Decrypt for another key error = %v; want ErrDecrypt", err)
+	}
+
+	other, err := NewCipher(bytes.Repeat([]byte{2}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{enc, "hunter2", EncryptedPrefix + "!!", EncryptedPrefix + "AAAA"} {
+		if _, err := other.Decrypt("db.password", value); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("Decrypt(%q) error = %v; want ErrDecrypt", value, err)
+		}
+	}
+
+	if _, err := NewCipher([]byte("short")); err == nil {
+		t.Error("NewCipher with a 5-byte key succeeded")
+	}
+}
+
+func TestCipher_resolver(t *testing.T) {
+	c := testCipher(t)
+	enc, err := c.Encrypt("db.password", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dsn, err := c.Encrypt("db.dsn", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec := Reader{Resolver: c}
+	v := make(Values)
+	src := "[db]\nhost = localhost\npassword = " + enc + "\ndsn = " + dsn + "\n"
+	if err := dec.Read(strings.NewReader(src), v); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.Get("db.password"); got != "hunter2" {
+		t.Errorf("db.password = %q; want %q", got, "hunter2")
+	}
+	if got := v.Get("db.dsn"); got != "hunter2" {
+		t.Errorf("db.dsn = %q; want %q", got, "hunter2")
+	}
+	if got := v.Get("db.host"); got != "localhost" {
+		t.Errorf("db.host = %q; want %q", got, "localhost")
+	}
+
+	// With MarkSecrets, secrets of keys that are not secret keys keep SecretPrefix
+	marked := Reader{Resolver: c, MarkSecrets: true}
+	v = make(Values)
+	if err := marked.Read(strings.NewReader(src), v); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.Get("db.dsn"); got != SecretPrefix+"hunter2" {
+		t.Errorf("db.dsn with MarkSecrets = %q; want %q", got, SecretPrefix+"hunter2")
+	}
+	if got := v.Get("db.password"); got != "hunter2" {
+		t.Errorf("db.password with MarkSecrets = %q; want %q", got, "hunter2")
+	}
+
+	// A value copied to another key does not decrypt
+	var serr *SecretError
+	err = dec.Read(strings.NewReader("[db]\ndsn = "+enc+"\n"), make(Values))
+	if !errors.As(err, &serr) || serr.Key != "db.dsn" || !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Read of copied value error = %v; want *SecretError wrapping ErrDecrypt", err)
+	}
+
+	err = dec.Read(strings.NewReader("k = enc:v2:AAAA\n"), make(Values))
+	if !errors.As(err, &serr) || serr.Key != "k" || !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Read of enc:v2 error = %v; want *SecretError wrapping ErrDecrypt", err)
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), "ini.key")
+	if err := WriteKeyFile(name, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteKeyFile(name, key); !errors.Is(err, os.ErrExist) {
+		t.Errorf("WriteKeyFile over existing file error = %v; want os.ErrExist", err)
+	}
+	if fi, err := os.Stat(name); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v; want %v", fi.Mode().Perm(), os.FileMode(0o600))
+	}
+
+	c, err := LoadKeyFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := c.Encrypt("k", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := want.Decrypt("k", enc); err != nil || got != "x" {
+		t.Errorf("Decrypt = %q, %v; want %q", got, err, "x")
+	}
+}
+
+func TestDocument_Encrypt(t *testing.T) {
+	c := testCipher(t)
+	const src = "; creds\n[db]\nhost = localhost ; local\npassword=\"a b\" # keep\npassword = c\nflag = 1\n"
+	doc, err := ParseDocument([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Encrypt("db.password", c); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Encrypt("db.flag", c); err != nil {
+		t.Fatal(err)
+	}
+
+	out := string(doc.Bytes())
+	lines := strings.Split(out, "\n")
+	if len(lines) != 7 || lines[0] != "; creds" || lines[2] != "host = localhost ; local" {
+		t.Fatalf("Bytes() = %q; want other lines unchanged", out)
+	}
+	wants := []struct {
+		line, prefix, suffix, key, plain string
+	}{
+		{lines[3], "password=", " # keep", "db.password", "a b"},
+		{lines[4], "password = ", "", "db.password", "c"},
+		{lines[5], "flag = ", "", "db.flag", "1"},
+	}
+	for _, w := range wants {
+		enc, okPrefix := strings.CutPrefix(w.line, w.prefix)
+		enc, okSuffix := strings.CutSuffix(enc, w.suffix)
+		if !okPrefix || !okSuffix {
+			t.Errorf("line = %q; want %q...%q", w.line, w.prefix, w.suffix)
+			continue
+		}
+		if got, err := c.Decrypt(w.key, enc); err != nil || got != w.plain {
+			t.Errorf("Decrypt(%q) = %q, %v; want %q", enc, got, err, w.plain)
+		}
+	}
+
+	// Encrypted values are not encrypted again
+	if err := doc.Encrypt("db.password", c); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(doc.Bytes()); got != out {
+		t.Errorf("Bytes() after second Encrypt = %q; want %q", got, out)
+	}
+	if err := doc.Encrypt("db.missing", c); err == nil {
+		t.Error("Encrypt of missing key succeeded")
+	}
+
+	// Keys without values and values of other versions are not encrypted
+	for _, src := range []string{"k = 1\nk\n", "k = 1\nk = enc:v2:AAAA\n"} {
+		doc, err := ParseDocument([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.Encrypt("k", c); err == nil {
+			t.Errorf("Encrypt(%q) succeeded", src)
+		} else if got := string(doc.Bytes()); got != src {
+			t.Errorf("Bytes() after failed Encrypt = %q; want %q", got, src)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package ini
+
+import (
+	"iter"
+	"sort"
+	"strings"
+)
+
+// Defaults is a view of Values in which a key missing from its section falls back to the key of the
+// same name in a default section, similar to the DEFAULT section of Python's configparser. Values
+// set explicitly in a section always take precedence over the default section's values.
+//
+// Defaults does not copy the Values it views, so changes to the Values are visible through it.
+type Defaults struct {
+	// Values is the set of values viewed.
+	Values Values
+	// Section is the name of the default section as it appears in keys. For example, given the
+	// heading "[DEFAULT]" and a case-sensitive Reader, Section is "DEFAULT".
+	Section string
+	// Separator is the string between a section name and a key. If Separator is the empty
+	// string, it defaults to "." (period). If Separator is None, keys have no sections and nothing
+	// falls back to the default section.
+	Separator string
+}
+
+// WithDefaults returns a view of the receiver that resolves keys missing from a section to the same
+// key in the given default section. The view uses the default separator, "." (period).
+func (v Values) WithDefaults(section string) *Defaults {
+	return &Defaults{Values: v, Section: section}
+}
+
+func (d *Defaults) sep() string {
+	return separatorOrDefault(d.Separator)
+}
+
+// split returns the section and name of key. If key has no section, ok is false.
+func (d *Defaults) split(key string) (section, name string, ok bool) {
+	sep := d.sep()
+	i := strings.LastIndex(key, sep)
+	if i == -1 || sep == "" {
+		return "", key, false
+	}
+	return key[:i], key[i+len(sep):], true
+}
+
+// isDefault returns whether section is the default section or one of its subsections.
+func (d *Defaults) isDefault(section string) bool {
+	return section == d.Section || strings.HasPrefix(section, d.Section+d.sep())
+}
+
+// Lookup returns the values for key and whether they were found. If key is not defined, the key of
+// the same name in the default section is returned instead. Keys without a section and keys in the
+// default section do not fall back to anything.
+func (d *Defaults) Lookup(key string) ([]string, bool) {
+	if vs, ok := d.Values[key]; ok {
+		return vs, true
+	}
+
+	section, name, ok := d.split(key)
+	if !ok || d.isDefault(section) {
+		return nil, false
+	}
+	vs, ok := d.Values[d.Section+d.sep()+name]
+	return vs, ok
+}
+
+// Get returns the first value for key, falling back to the default section if key is not defined.
+// If neither key nor its default exist or the resolved value slice is empty, Get returns an empty
+// string.
+func (d *Defaults) Get(key string) string {
+	if vs, _ := d.Lookup(key); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// Contains returns true if key is defined either in its own section or the default section.
+func (d *Defaults) Contains(key string) bool {
+	_, ok := d.Lookup(key)
+	return ok
+}
+
+// Sections returns an iterator over the names of all sections with keys in the viewed Values, in
+// sorted order, excluding the default section and its subsections.
+func (d *Defaults) Sections() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, s := range d.sections() {
+			if !yield(s) {
+				return
+			}
+		}
+	}
+}
+
+// sections returns the sorted names of all sections with keys in the viewed Values, excluding the
+// default section and its subsections.
+func (d *Defaults) sections() []string {
+	seen := make(map[string]struct{})
+	for k := range d.Values {
+		section, _, ok := d.split(k)
+		if !ok || d.isDefault(section) {
+			continue
+		}
+		seen[section] = struct{}{}
+	}
+
+	sections := make([]string, 0, len(seen))
+	for s := range seen {
+		sections = append(sections, s)
+	}
+	sort.Strings(sections)
+	return sections
+}
+
+// All returns an iterator over every resolved key and value of the view, as Copy would return
+// them. Keys are visited in sorted order, and keys in the default section and its subsections are
+// not visited.
+func (d *Defaults) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for k, v := range d.Copy(nil).All() {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Copy copies the resolved values of the view to dst, allocating dst if it is nil. Every section
+// other than the default section receives the default section's keys that it does not define
+// itself. The default section and its subsections are not copied. As with Values.Copy, values are
+// appended to any already present in dst.
+func (d *Defaults) Copy(dst Values) Values {
+	if dst == nil {
+		dst = make(Values, len(d.Values))
+	}
+
+	defaults := make(map[string][]string)
+	for k, vs := range d.Values {
+		section, name, ok := d.split(k)
+		if ok && d.isDefault(section) {
+			if section == d.Section {
+				defaults[name] = vs
+			}
+			continue
+		}
+		dst[k] = append(dst[k], vs...)
+	}
+
+	for _, section := range d.sections() {
+		for name, vs := range defaults {
+			k := section + d.sep() + name
+			if _, ok := d.Values[k]; ok {
+				continue
+			}
+			dst[k] = append(dst[k], vs...)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,77 @@
+package ini
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestDefaults(t *testing.T) {
+	v, err := ReadINI([]byte(`
+[DEFAULT]
+timeout = 30
+retries = 3
+
+[server]
+timeout = 10
+
+[client]
+name = foo
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := v.WithDefaults("DEFAULT")
+	check := func(k, want string) {
+		if got := d.Get(k); got != want {
+			t.Errorf("d.Get(%q) = %q; want %q", k, got, want)
+		}
+	}
+
+	check("server.timeout", "10")
+	check("server.retries", "3")
+	check("client.timeout", "30")
+	check("client.name", "foo")
+	check("client.missing", "")
+	check("timeout", "")
+	check("DEFAULT.timeout", "30")
+
+	if got, want := slices.Collect(d.Sections()), []string{"client", "server"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("d.Sections() = %q; want %q", got, want)
+	}
+
+	var keys []string
+	for k, v := range d.All() {
+		keys = append(keys, k+"="+v)
+	}
+	wantKeys := []string{"client.name=foo", "client.retries=3", "client.timeout=30", "server.retries=3", "server.timeout=10"}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("d.All() = %q; want %q", keys, wantKeys)
+	}
+
+	want := Values{
+		"server.timeout": []string{"10"},
+		"server.retries": []string{"3"},
+		"client.timeout": []string{"30"},
+		"client.retries": []string{"3"},
+		"client.name":    []string{"foo"},
+	}
+	if got := d.Copy(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("d.Copy(nil) = %#v; want %#v", got, want)
+	}
+}
+
+func TestDefaults_separator(t *testing.T) {
+	v := Values{
+		"defaults:a": []string{"1"},
+		"x:b":        []string{"2"},
+	}
+	d := &Defaults{Values: v, Section: "defaults", Separator: ":"}
+	if got := d.Get("x:a"); got != "1" {
+		t.Errorf("d.Get(%q) = %q; want %q", "x:a", got, "1")
+	}
+	if !d.Contains("x:b") || d.Contains("x:c") {
+		t.Errorf("d.Contains returned unexpected results")
+	}
+}
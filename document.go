@@ -0,0 +1,588 @@
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Document is an INI file that can be edited in place. Unlike Values, a Document keeps the input
+// it was parsed from, so comments, blank lines, and the formatting of keys and values that are not
+// changed are preserved when it is written out again.
+//
+// Keys in a Document are the same as the keys a Reader produces, including section prefixes. A
+// Document is parsed and edited using the configuration of the Reader that created it. Input in an
+// encoding other than UTF-8 is held decoded and encoded again when the Document is written, so an
+// edit that adds a character the encoding cannot hold returns an error wrapping ErrUnencodable.
+type Document struct {
+	cfg      Reader   // cfg reads src, which is always UTF-8
+	enc      Encoding // enc is the encoding of the Document's input and output
+	bom      []byte   // bom is the byte order mark of transcoded input, if it had one
+	src      []byte
+	entries  []docEntry
+	sections []docSection
+}
+
+// docEntry is a key and its value, if any, in a Document.
+type docEntry struct {
+	key      Token
+	value    Token
+	hasValue bool
+	section  int // section is the index of the entry's section in Document.sections
+}
+
+// end returns the offset of the end of the entry in the Document's source.
+func (e *docEntry) end() int {
+	if e.hasValue {
+		return tokenEnd(e.value)
+	}
+	return tokenEnd(e.key)
+}
+
+// docSection is a section heading in a Document. The first docSection of every Document is the
+// section of keys that precede any heading and has no heading token.
+type docSection struct {
+	name    string
+	heading Token
+}
+
+// tokenEnd returns the offset of the end of tok in its input.
+func tokenEnd(tok Token) int {
+	return int(tok.Offset) + len(tok.Raw)
+}
+
+// ParseDocument parses src as DefaultDecoder and returns it as a Document.
+func ParseDocument(src []byte) (*Document, error) {
+	return DefaultDecoder.ParseDocument(src)
+}
+
+// ParseDocument parses src and returns it as a Document. The Document keeps a copy of the receiver
+// and of src, so neither is referenced after ParseDocument returns.
+func (d *Reader) ParseDocument(src []byte) (*Document, error) {
+	doc := &Document{cfg: *d, enc: d.encoding()}
+	if doc.enc == DetectEncoding {
+		doc.enc = detectUTF16(src)
+	}
+	if doc.enc.transcoded() {
+		// Token offsets are offsets in the decoded input, so edits are made to it
+		if bom := doc.enc.bom(); bom != nil && bytes.HasPrefix(src, bom) {
+			doc.bom = bom
+		}
+		src = decodeBytes(doc.enc, src)
+		doc.cfg.Encoding = UTF8
+	} else {
+		src = append([]byte(nil), src...)
+	}
+
+	if err := doc.edit(src); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// derive returns a new Document holding src, with the configuration and encoding of the receiver.
+// As with the receiver's source, src is UTF-8.
+func (doc *Document) derive(src []byte) (*Document, error) {
+	d := &Document{cfg: doc.cfg, enc: doc.enc, bom: doc.bom}
+	if err := d.edit(src); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// parse scans the Document's source, replacing its entries and sections.
+func (doc *Document) parse() error {
+	entries, sections := doc.entries[:0], doc.sections[:0]
+	sections = append(sections, docSection{})
+
+	s := doc.cfg.NewScanner(bytes.NewReader(doc.src))
+	for s.Next() {
+		switch tok := s.Token(); tok.Kind {
+		case SectionStart:
+			sections = append(sections, docSection{name: tok.Text, heading: tok})
+		case Key:
+			entries = append(entries, docEntry{key: tok, section: len(sections) - 1})
+		case Value:
+			e := &entries[len(entries)-1]
+			e.value, e.hasValue = tok, true
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	doc.entries, doc.sections = entries, sections
+	return nil
+}
+
+// edit replaces the Document's source with src and parses it. If src cannot be parsed or cannot be
+// written in the Document's encoding, the Document is left unchanged and the error is returned.
+func (doc *Document) edit(src []byte) error {
+	if _, err := encodeBytes(doc.enc, src); err != nil {
+		return err
+	}
+
+	prev := *doc
+	doc.src = src
+	if err := doc.parse(); err != nil {
+		*doc = prev
+		return err
+	}
+	return nil
+}
+
+// splice returns a copy of the Document's source with the bytes from start to end replaced by s.
+func (doc *Document) splice(start, end int, s string) []byte {
+	src := make([]byte, 0, len(doc.src)-(end-start)+len(s))
+	src = append(src, doc.src[:start]...)
+	src = append(src, s...)
+	return append(src, doc.src[end:]...)
+}
+
+// value returns the value of e.
+func (doc *Document) value(e *docEntry) string {
+	if e.hasValue {
+		return e.value.Text
+	}
+	switch doc.cfg.True {
+	case None:
+		return ""
+	case "":
+		return True
+	default:
+		return doc.cfg.True
+	}
+}
+
+// Get returns the first value of key and whether key is defined. A key with no value has the
+// Reader's True value.
+func (doc *Document) Get(key string) (string, bool) {
+	for i := range doc.entries {
+		if e := &doc.entries[i]; e.key.Text == key {
+			return doc.value(e), true
+		}
+	}
+	return "", false
+}
+
+// GetAll returns all values of key in the order they occur in the Document.
+func (doc *Document) GetAll(key string) []string {
+	var values []string
+	for i := range doc.entries {
+		if e := &doc.entries[i]; e.key.Text == key {
+			values = append(values, doc.value(e))
+		}
+	}
+	return values
+}
+
+// Values returns the keys and values of the Document.
+func (doc *Document) Values() Values {
+	v := make(Values, len(doc.entries))
+	for i := range doc.entries {
+		e := &doc.entries[i]
+		v.Add(e.key.Text, doc.value(e))
+	}
+	return v
+}
+
+// Keys returns the keys of the Document in the order they first occur.
+func (doc *Document) Keys() []string {
+	seen := make(map[string]struct{}, len(doc.entries))
+	keys := make([]string, 0, len(doc.entries))
+	for _, e := range doc.entries {
+		if _, ok := seen[e.key.Text]; !ok {
+			seen[e.key.Text] = struct{}{}
+			keys = append(keys, e.key.Text)
+		}
+	}
+	return keys
+}
+
+// Sections returns the names of the Document's sections in the order they first occur. A section
+// heading that occurs more than once is only returned once, and an empty heading ("[]") is not
+// returned.
+func (doc *Document) Sections() []string {
+	seen := make(map[string]struct{}, len(doc.sections))
+	var sections []string
+	for _, s := range doc.sections[1:] {
+		if _, ok := seen[s.name]; !ok && s.name != "" {
+			seen[s.name] = struct{}{}
+			sections = append(sections, s.name)
+		}
+	}
+	return sections
+}
+
+// Set sets key to value. If key is defined, its first occurrence is given value and any other
+// occurrences are removed. Otherwise, key is added as by Add. Set returns an error if key cannot be
+// written in the Document (for example, if it contains whitespace), or an error wrapping
+// ErrInvalidValue if value does not read back as itself once written, in which case the Document
+// is left unchanged.
+func (doc *Document) Set(key, value string) error {
+	if err := doc.checkKey(key); err != nil {
+		return err
+	}
+
+	first := -1
+	for i := range doc.entries {
+		if doc.entries[i].key.Text == key {
+			first = i
+			break
+		}
+	}
+	if first == -1 {
+		return doc.Add(key, value)
+	}
+
+	// Remove later occurrences first so that the offsets of the first remain valid
+	prev := *doc
+	for i := len(doc.entries) - 1; i > first; i-- {
+		if doc.entries[i].key.Text == key {
+			if err := doc.edit(doc.remove(&doc.entries[i])); err != nil {
+				*doc = prev
+				return err
+			}
+		}
+	}
+
+	if err := doc.edit(doc.replaceValue(&doc.entries[first], value)); errors.Is(err, ErrUnencodable) {
+		*doc = prev
+		return err
+	} else if err != nil {
+		*doc = prev
+		return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	if values := doc.GetAll(key); len(values) != 1 || values[0] != value {
+		// The value was written but does not read back as the same value
+		*doc = prev
+		return ErrInvalidValue
+	}
+	return nil
+}
+
+// checkKey returns an error if key has a form that can never be written in the Document.
+func (doc *Document) checkKey(key string) error {
+	if !doc.cfg.ArrayKeys || !strings.ContainsRune(key, rSectionOpen) {
+		return nil
+	}
+
+	// Subscripts are read as part of the key, so a key holding one is never read back
+	name, rest, _ := strings.Cut(key, string(rSectionOpen))
+	for _, sub := range strings.Split(rest, string(rSectionOpen)) {
+		if sub = strings.TrimSuffix(sub, string(rSectionClose)); sub != "" {
+			name += doc.cfg.KeySeparator() + sub
+		}
+	}
+	return fmt.Errorf("%w: %q has a subscript, which ArrayKeys reads as key %q", ErrInvalidKey, key, name)
+}
+
+// replaceValue returns the Document's source with the value of e replaced by value. The rest of the
+// source, including the text of e's key, is unchanged.
+func (doc *Document) replaceValue(e *docEntry, value string) []byte {
+	quoted := doc.cfg.formatValue(value)
+	switch {
+	case !e.hasValue:
+		return doc.splice(e.end(), e.end(), " = "+quoted)
+	case e.value.Raw == "":
+		if off := int(e.value.Offset); off > 0 && doc.src[off-1] == rEquals {
+			quoted = " " + quoted
+		}
+	}
+	return doc.splice(int(e.value.Offset), e.end(), quoted)
+}
+
+// Add adds value to key, after the last key in key's section. If key's section is not in the
+// Document, a heading for it is added to the end of the Document. Add returns an error if key
+// cannot be written in the Document, or an error wrapping ErrInvalidValue if value does not read
+// back as itself once written.
+func (doc *Document) Add(key, value string) error {
+	if err := doc.checkKey(key); err != nil {
+		return err
+	}
+
+	sep := doc.cfg.KeySeparator()
+	sect := 0
+	for i, s := range doc.sections {
+		if s.name != "" && strings.HasPrefix(key, s.name+sep) && len(s.name) >= len(doc.sections[sect].name) {
+			sect = i
+		}
+	}
+
+	var src []byte
+	if sect == 0 && sep != "" && strings.Contains(key, sep) && !doc.cfg.Properties && !doc.cfg.DotEnv {
+		// Add a new section to the end of the document
+		i := strings.LastIndex(key, sep)
+		heading, err := doc.heading(key[:i])
+		if err != nil {
+			return err
+		}
+
+		var b strings.Builder
+		if len(doc.src) > 0 {
+			if doc.src[len(doc.src)-1] != rNewline {
+				b.WriteByte(rNewline)
+			}
+			b.WriteByte(rNewline)
+		}
+		fmt.Fprintf(&b, "%s\n%s = %s\n", heading, key[i+len(sep):], doc.cfg.formatValue(value))
+		src = doc.splice(len(doc.src), len(doc.src), b.String())
+	} else {
+		src = doc.insert(sect, key[len(doc.sections[sect].name):], value)
+	}
+
+	prev, n := *doc, len(doc.GetAll(key))
+	if err := doc.edit(src); errors.Is(err, ErrUnencodable) {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	if values := doc.GetAll(key); len(values) != n+1 {
+		// The key was written but does not read back as the same key
+		*doc = prev
+		return ErrInvalidKey
+	} else if values[n] != value {
+		*doc = prev
+		return ErrInvalidValue
+	}
+	return nil
+}
+
+// insert returns the Document's source with a line setting name to value inserted after the last
+// entry of the section at index sect, or after its heading if it has no entries.
+func (doc *Document) insert(sect int, name, value string) []byte {
+	sep := doc.cfg.KeySeparator()
+	if sect > 0 {
+		name = strings.TrimPrefix(name, sep)
+	}
+
+	// Find the last occurrence of the section
+	for i := len(doc.sections) - 1; i > sect; i-- {
+		if doc.sections[i].name == doc.sections[sect].name {
+			sect = i
+			break
+		}
+	}
+
+	var last *docEntry
+	for i := range doc.entries {
+		if doc.entries[i].section == sect {
+			last = &doc.entries[i]
+		}
+	}
+
+	if doc.cfg.Properties {
+		name = propString(name, true)
+	}
+	line := name + " = " + doc.cfg.formatValue(value)
+	switch {
+	case last != nil:
+		// Match the indentation of the last entry's line
+		start := bytes.LastIndexByte(doc.src[:last.key.Offset], rNewline) + 1
+		indent := doc.src[start:last.key.Offset]
+		if len(bytes.TrimLeft(indent, " \t")) != 0 {
+			indent = nil
+		}
+		return doc.insertLine(last.end(), string(indent)+line)
+	case sect > 0:
+		return doc.insertLine(tokenEnd(doc.sections[sect].heading), line)
+	default:
+		return doc.splice(0, 0, line+"\n")
+	}
+}
+
+// insertLine returns the Document's source with line inserted after the line containing offset.
+func (doc *Document) insertLine(offset int, line string) []byte {
+	end := bytes.IndexByte(doc.src[offset:], rNewline)
+	if end == -1 {
+		end = len(doc.src)
+	} else {
+		end += offset
+		if end > 0 && doc.src[end-1] == '\r' {
+			end--
+		}
+	}
+	return doc.splice(end, end, "\n"+line)
+}
+
+// heading returns a section heading for the section name.
+func (doc *Document) heading(name string) (string, error) {
+	segments := []string{name}
+	if sep := doc.cfg.KeySeparator(); sep != "" {
+		segments = strings.Split(name, sep)
+	}
+
+	var b strings.Builder
+	b.WriteByte(rSectionOpen)
+	for i, seg := range segments {
+		if seg == "" {
+			return "", ErrInvalidKey
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if doc.bareSegment(seg) {
+			b.WriteString(seg)
+		} else {
+			b.WriteString(quoteString(seg))
+		}
+	}
+	b.WriteByte(rSectionClose)
+	return b.String(), nil
+}
+
+// bareSegment returns whether seg may be written unquoted in a section heading.
+func (doc *Document) bareSegment(seg string) bool {
+	for _, r := range seg {
+		switch {
+		case r == '-' || r == '_' || r == '.':
+		case unicode.IsLetter(r):
+			if doc.cfg.Casing == LowerCase && !unicode.IsLower(r) ||
+				doc.cfg.Casing == UpperCase && !unicode.IsUpper(r) {
+				return false
+			}
+		case unicode.IsDigit(r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Unset removes every occurrence of key from the Document and returns whether key was defined.
+// Lines left empty by removing key are removed as well, including any comment following key on
+// the same line. If the Document cannot be parsed after removing key, Unset returns an error and
+// the Document is left unchanged.
+func (doc *Document) Unset(key string) (bool, error) {
+	prev := *doc
+	found := false
+	for i := len(doc.entries) - 1; i >= 0; i-- {
+		if doc.entries[i].key.Text != key {
+			continue
+		}
+		found = true
+		if err := doc.edit(doc.remove(&doc.entries[i])); err != nil {
+			*doc = prev
+			return false, fmt.Errorf("ini: removing key %q: %w", key, err)
+		}
+	}
+	return found, nil
+}
+
+// remove returns the Document's source with e removed.
+func (doc *Document) remove(e *docEntry) []byte {
+	start, end := int(e.key.Offset), e.end()
+
+	lineStart := bytes.LastIndexByte(doc.src[:start], rNewline) + 1
+	lineEnd := bytes.IndexByte(doc.src[end:], rNewline)
+	if lineEnd == -1 {
+		lineEnd = len(doc.src)
+	} else {
+		lineEnd += end
+	}
+
+	before := doc.src[lineStart:start]
+	after := bytes.TrimLeft(doc.src[end:lineEnd], " \t\r")
+	if len(bytes.TrimLeft(before, " \t")) != 0 {
+		// Something (e.g., a section heading) precedes the key on its line
+		return doc.splice(start, end, "")
+	}
+	if len(after) > 0 && after[0] != rHash && after[0] != rSemicolon {
+		return doc.splice(start, end, "")
+	}
+
+	if lineEnd < len(doc.src) {
+		lineEnd++
+	} else if lineStart > 0 {
+		lineStart--
+	}
+	return doc.splice(lineStart, lineEnd, "")
+}
+
+// Bytes returns the Document's source, in the encoding of its input. The returned slice must not
+// be modified.
+func (doc *Document) Bytes() []byte {
+	// Edits are checked by edit, so the source can always be encoded
+	b, _ := encodeBytes(doc.enc, doc.src)
+	if n := len(doc.bom); n > 0 {
+		b = append(doc.bom[:n:n], b...)
+	}
+	return b
+}
+
+// WriteTo writes the Document's source to w, in the encoding of its input.
+func (doc *Document) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(doc.Bytes())
+	return int64(n), err
+}
+
+// formatValue returns value as it should be written in input read by the receiver: escaped for a
+// properties file if it reads Properties, quoted as by WriteDotEnv if it reads DotEnv, and quoted
+// as INI otherwise.
+func (d *Reader) formatValue(value string) string {
+	switch {
+	case d.Properties:
+		return propString(value, false)
+	case d.DotEnv:
+		return quoteEnvValue(value)
+	default:
+		return quoteValue(value)
+	}
+}
+
+// quoteValue returns value as it should be written in INI input. Values that would be read back
+// the same are returned unchanged, and others, including those that are not valid UTF-8, are
+// returned as a quoted string.
+func quoteValue(value string) string {
+	if value == "" || strings.HasPrefix(value, "<<") || !utf8.ValidString(value) {
+		return quoteString(value)
+	}
+
+	first, _ := utf8.DecodeRuneInString(value)
+	last, _ := utf8.DecodeLastRuneInString(value)
+	if unicode.IsSpace(first) || unicode.IsSpace(last) || first == rQuote || first == rRawQuote ||
+		strings.ContainsAny(value, "\n\r;#") || strings.IndexFunc(value, unicode.IsControl) != -1 {
+		return quoteString(value)
+	}
+	return value
+}
+
+// quoteString returns s as a double-quoted string, escaping quotes, backslashes, control
+// characters, and bytes that are not valid UTF-8.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte(rQuote)
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				fmt.Fprintf(&b, `\x%02x`, s[i])
+				continue
+			}
+		}
+
+		switch r {
+		case rQuote, rEscape:
+			b.WriteByte(rEscape)
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\x%02x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte(rQuote)
+	return b.String()
+}
@@ -0,0 +1,256 @@
+package ini
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testDocument(t *testing.T, dec *Reader, src string) *Document {
+	t.Helper()
+	if dec == nil {
+		dec = &DefaultDecoder
+	}
+	doc, err := dec.ParseDocument([]byte(src))
+	if err != nil {
+		t.Fatalf("ParseDocument(%q) = %v", src, err)
+	}
+	return doc
+}
+
+func testDocumentSource(t *testing.T, doc *Document, want string) {
+	t.Helper()
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("document =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDocument_Get(t *testing.T) {
+	doc := testDocument(t, nil, "; comment\na = 1\nflag\n[s \"T\"]\nk = \"x y\" ; c\nk = 2\n")
+	if got, ok := doc.Get("a"); !ok || got != "1" {
+		t.Errorf("Get(a) = %q, %t; want 1, true", got, ok)
+	}
+	if got, ok := doc.Get("flag"); !ok || got != True {
+		t.Errorf("Get(flag) = %q, %t; want %q, true", got, ok, True)
+	}
+	if _, ok := doc.Get("missing"); ok {
+		t.Error("Get(missing) = true; want false")
+	}
+	if got, want := doc.GetAll("s.T.k"), []string{"x y", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAll(s.T.k) = %q; want %q", got, want)
+	}
+	if got, want := doc.Keys(), []string{"a", "flag", "s.T.k"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %q; want %q", got, want)
+	}
+	if got, want := doc.Sections(), []string{"s.T"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sections() = %q; want %q", got, want)
+	}
+	want := Values{"a": {"1"}, "flag": {True}, "s.T.k": {"x y", "2"}}
+	if got := doc.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v; want %v", got, want)
+	}
+}
+
+func TestDocument_Set(t *testing.T) {
+	doc := testDocument(t, nil, "; header\na = 1 ; keep\n\n[s]\n  k = old\n  flag\n  e =\n  k = dup # gone\n\n[t]\n")
+
+	for _, kv := range [][2]string{
+		{"a", "new value"},
+		{"s.k", " padded "},
+		{"s.flag", "on"},
+		{"s.e", "x"},
+		{"s.added", "a;b"},
+		{"t.k", "1"},
+		{"u.v.w", ""},
+		{"top", "2"},
+	} {
+		if err := doc.Set(kv[0], kv[1]); err != nil {
+			t.Fatalf("Set(%q, %q) = %v", kv[0], kv[1], err)
+		}
+		if got, _ := doc.Get(kv[0]); got != kv[1] {
+			t.Errorf("Get(%q) = %q; want %q", kv[0], got, kv[1])
+		}
+	}
+
+	testDocumentSource(t, doc, "; header\n"+
+		"a = new value ; keep\n"+
+		"top = 2\n"+
+		"\n"+
+		"[s]\n"+
+		"  k = \" padded \"\n"+
+		"  flag = on\n"+
+		"  e = x\n"+
+		"  added = \"a;b\"\n"+
+		"\n"+
+		"[t]\n"+
+		"k = 1\n"+
+		"\n"+
+		"[u v]\n"+
+		"w = \"\"\n")
+}
+
+func TestDocument_SetInvalid(t *testing.T) {
+	const src = "[s]\nk = v\n"
+	doc := testDocument(t, &Reader{}, src)
+	for _, key := range []string{"s.K", "s.a b", "s.a=b", "x..y"} {
+		if err := doc.Set(key, "v"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Set(%q) = %v; want %v", key, err, ErrInvalidKey)
+		}
+	}
+	testDocumentSource(t, doc, src)
+
+	// Case-sensitive section names are quoted
+	if err := doc.Set("S.k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	testDocumentSource(t, doc, src+"\n[\"S\"]\nk = v\n")
+}
+
+func TestDocument_SetDialects(t *testing.T) {
+	doc := testDocument(t, &PropertiesDecoder, "a=1\n")
+	for _, kv := range [][2]string{{"a", " x;y"}, {"b c", ":v"}} {
+		if err := doc.Set(kv[0], kv[1]); err != nil {
+			t.Fatalf("Set(%q, %q) = %v", kv[0], kv[1], err)
+		}
+		if got, _ := doc.Get(kv[0]); got != kv[1] {
+			t.Errorf("Get(%q) = %q; want %q", kv[0], got, kv[1])
+		}
+	}
+	testDocumentSource(t, doc, "a=\\ x;y\nb\\ c = \\:v\n")
+
+	doc = testDocument(t, &Reader{DotEnv: true, Casing: CaseSensitive}, "A=1\n")
+	for _, kv := range [][2]string{{"A", "x $y"}, {"B", "it's $y"}} {
+		if err := doc.Set(kv[0], kv[1]); err != nil {
+			t.Fatalf("Set(%q, %q) = %v", kv[0], kv[1], err)
+		}
+		if got, _ := doc.Get(kv[0]); got != kv[1] {
+			t.Errorf("Get(%q) = %q; want %q", kv[0], got, kv[1])
+		}
+	}
+	testDocumentSource(t, doc, "A='x $y'\nB = \"it's \\$y\"\n")
+
+	// Keys with subscripts are read as other keys
+	const src = "k[] = 1\n"
+	doc = testDocument(t, &Reader{ArrayKeys: true}, src)
+	for _, key := range []string{"k[]", "k[a]"} {
+		if err := doc.Set(key, "v"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Set(%q) = %v; want %v", key, err, ErrInvalidKey)
+		}
+	}
+	testDocumentSource(t, doc, src)
+}
+
+func TestDocument_Unset(t *testing.T) {
+	doc := testDocument(t, nil, "a = 1\n[s] k = 1\n  k = 2 ; comment\n  j = 3\nk")
+	if ok, err := doc.Unset("s.k"); !ok || err != nil {
+		t.Errorf("Unset(s.k) = %t, %v; want true, nil", ok, err)
+	}
+	if ok, _ := doc.Unset("s.k"); ok {
+		t.Error("Unset(s.k) = true after removal; want false")
+	}
+	testDocumentSource(t, doc, "a = 1\n[s] \n  j = 3")
+
+	doc.Unset("s.j")
+	testDocumentSource(t, doc, "a = 1\n[s] ")
+	doc.Unset("a")
+	testDocumentSource(t, doc, "[s] ")
+}
+
+func TestQuoteValue(t *testing.T) {
+	cases := map[string]string{
+		"":         `""`,
+		"plain":    "plain",
+		"a b":      "a b",
+		" a":       `" a"`,
+		"a\n":      `"a\n"`,
+		`"q"`:      `"\"q\""`,
+		"`r`":      "\"`r`\"",
+		"a # b":    `"a # b"`,
+		`back\sla`: `back\sla`,
+		"<<EOF":    `"<<EOF"`,
+		"\x01":     `"\x01"`,
+		"a\x80b":   `"a\x80b"`,
+		"\xff\xfe": `"\xff\xfe"`,
+		"\uFFFD":   "\uFFFD",
+	}
+	for in, want := range cases {
+		if got := quoteValue(in); got != want {
+			t.Errorf("quoteValue(%q) = %s; want %s", in, got, want)
+		}
+		v, err := ReadINI([]byte("k = "+quoteValue(in)), nil)
+		if err != nil {
+			t.Errorf("ReadINI(%q) = %v", in, err)
+		} else if got := v.Get("k"); got != in {
+			t.Errorf("read back %q; want %q", got, in)
+		}
+	}
+}
+
+func TestDocument_editInvalid(t *testing.T) {
+	const src = "a = 1\n[s]\nk = 2\n"
+	doc := testDocument(t, nil, src)
+	if err := doc.edit([]byte("[unclosed\n")); err == nil {
+		t.Fatal("edit(invalid) = nil; want error")
+	}
+	testDocumentSource(t, doc, src)
+	if got, _ := doc.Get("s.k"); got != "2" {
+		t.Errorf("Get(s.k) = %q; want 2", got)
+	}
+}
+
+func TestDocument_latin1(t *testing.T) {
+	doc := testDocument(t, &PropertiesDecoder, "a=\xe9\nk=1\nb=2\n")
+	if err := doc.Set("k", "2"); err != nil {
+		t.Fatal(err)
+	}
+	testDocumentSource(t, doc, "a=\xe9\nk=2\nb=2\n")
+	if ok, err := doc.Unset("k"); !ok || err != nil {
+		t.Fatalf("Unset(k) = %t, %v; want true, nil", ok, err)
+	}
+	testDocumentSource(t, doc, "a=\xe9\nb=2\n")
+	if got, _ := doc.Get("a"); got != "é" {
+		t.Errorf("Get(a) = %q; want é", got)
+	}
+
+	// Properties values are escaped as ASCII, so any character can be written
+	if err := doc.Set("b", "€"); err != nil {
+		t.Fatal(err)
+	}
+	testDocumentSource(t, doc, "a=\xe9\nb=\\u20ac\n")
+
+	doc = testDocument(t, &Reader{Encoding: Latin1}, "a = \xe9\nb = 2\n")
+	if err := doc.Set("b", "€"); !errors.Is(err, ErrUnencodable) {
+		t.Errorf("Set(b, €) = %v; want %v", err, ErrUnencodable)
+	}
+	testDocumentSource(t, doc, "a = \xe9\nb = 2\n")
+
+	tmpl := testDocument(t, &Reader{Encoding: Windows1252}, "a = \x80\nk = {{v}}\n")
+	out, err := Render(tmpl, Values{"v": {"’"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDocumentSource(t, out, "a = \x80\nk = \x92\n")
+}
+
+func TestDocument_utf16(t *testing.T) {
+	for _, bigEndian := range []bool{false, true} {
+		doc := testDocument(t, nil, encodeUTF16("; café\n[a]\nx = 1\ny = 😀\n", bigEndian, true))
+		if err := doc.Set("a.x", "5"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := doc.Unset("a.y"); err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.Add("a.z", "ü"); err != nil {
+			t.Fatal(err)
+		}
+		testDocumentSource(t, doc, encodeUTF16("; café\n[a]\nx = 5\nz = ü\n", bigEndian, true))
+
+		// The edited Document reads back as it was written
+		doc = testDocument(t, nil, string(doc.Bytes()))
+		want := Values{"a.x": {"5"}, "a.z": {"ü"}}
+		if got := doc.Values(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Values() = %q; want %q", got, want)
+		}
+	}
+}
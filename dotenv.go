@@ -0,0 +1,193 @@
+package ini
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+const rSingleQuote = '\''
+
+// DotEnvDecoder is a Reader for dotenv files. Keys are case-sensitive and names are only expanded
+// from values set earlier in the input.
+var DotEnvDecoder = Reader{
+	Casing: CaseSensitive,
+	True:   True,
+	DotEnv: true,
+}
+
+// ReadDotEnv reads a dotenv file from b, writing the results to the out Values. If out is nil, a
+// new Values is allocated to store the results.
+//
+// ReadDotEnv is a convenience function for calling DotEnvDecoder.Read(bytes.NewReader(b), out),
+// except that it reads directly from b.
+func ReadDotEnv(b []byte, out Values) (Values, error) {
+	if out == nil {
+		out = make(Values)
+	}
+	if err := DotEnvDecoder.readBytes(b, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// readEnvValue reads a dotenv value following an equals sign.
+func (d *decoder) readEnvValue() (next nextfunc, err error) {
+	spaced := isHorizSpace(d.current)
+	if err = d.skipSpace(false); err == io.EOF {
+		return nil, d.addValue("", d.mark(), d.offset)
+	} else if err != nil {
+		return nil, err
+	}
+
+	d.valueStart = d.mark()
+	switch d.current {
+	case rNewline:
+		if err = d.addValue("", d.valueStart, d.offset); err != nil {
+			return nil, err
+		}
+		defer stopOnEOF(&next, &err)
+		return (*decoder).readElem, d.skip()
+	case rQuote:
+		return (*decoder).readStringValue, nil
+	case rSingleQuote:
+		return (*decoder).readLiteralValue, nil
+	case rHash:
+		if spaced {
+			return (*decoder).readComment, d.addValue("", d.valueStart, d.offset)
+		}
+	}
+	return d.readUnquotedEnvValue()
+}
+
+// readUnquotedEnvValue reads an unquoted dotenv value, starting with the current rune. The value
+// ends at a newline or a comment preceded by whitespace.
+func (d *decoder) readUnquotedEnvValue() (next nextfunc, err error) {
+	for {
+		switch d.current {
+		case rNewline:
+			return (*decoder).readElem, d.addEnvValue()
+		case rHash:
+			if b := d.buffer.Bytes(); len(b) > 0 && isHorizSpace(rune(b[len(b)-1])) {
+				err = d.addEnvValue()
+				d.buffer.Reset()
+				return (*decoder).readComment, err
+			}
+			d.buffer.WriteByte(rHash)
+		case '$':
+			if err = d.readExpansion(); err != nil {
+				return nil, err
+			}
+		default:
+			d.buffer.WriteRune(d.current)
+		}
+
+		if err = d.readUntil(setEnvValueEnd, true, nil); err == io.EOF {
+			return nil, d.addEnvValue()
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// addEnvValue adds the buffered unquoted value, less trailing whitespace.
+func (d *decoder) addEnvValue() error {
+	value := string(bytes.TrimRightFunc(d.buffer.Bytes(), unicode.IsSpace))
+	return d.addValue(value, d.valueStart, d.trimmedEnd(d.valueStart, d.offset))
+}
+
+// readLiteralValue reads a single-quoted dotenv value. Its contents are not escaped or expanded.
+func (d *decoder) readLiteralValue() (next nextfunc, err error) {
+	err = d.readUntil(setLiteral, true, nil)
+	if err == io.EOF {
+		return nil, d.syntaxerr(UnclosedError(rSingleQuote), "encountered EOF inside single-quoted string")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = d.addValue(d.buffer.String(), d.valueStart, d.offset+int64(d.size)); err != nil {
+		return nil, err
+	}
+	defer stopOnEOF(&next, &err)
+	return (*decoder).readElem, d.skip()
+}
+
+// readExpansion reads the name following a $ of the form {NAME} and writes its value to the
+// buffer. If the $ is not followed by {, the $ is written as-is.
+func (d *decoder) readExpansion() error {
+	if r, _, err := d.peekRune(); err != nil || r != '{' {
+		d.buffer.WriteByte('$')
+		return nil
+	}
+	if err := d.skip(); err != nil {
+		return err
+	}
+
+	start := d.buffer.Len()
+	err := d.readUntil(setExpansionEnd, true, nil)
+	if err == io.EOF || err == nil && d.current == rNewline {
+		return d.syntaxerr(UnclosedError('{'), "expected } to end variable name")
+	} else if err != nil {
+		return err
+	}
+
+	name := string(d.buffer.Bytes()[start:])
+	d.buffer.Truncate(start)
+	if name == "" {
+		return d.syntaxerr(ErrEmptyKey, "variable names may not be blank")
+	}
+
+	if value, ok := d.env[name]; ok {
+		d.buffer.WriteString(value)
+	} else if d.getenv != nil {
+		d.buffer.WriteString(d.getenv(name))
+	}
+	return nil
+}
+
+// WriteDotEnv writes v to w as a dotenv file, one KEY=value line per value in sorted key order.
+// Values are unquoted if possible and single-quoted if they contain no single quotes. Otherwise,
+// they are double-quoted and escaped, including any $. Keys without values are not written.
+// WriteDotEnv returns an error if a key cannot be written as a dotenv key.
+func WriteDotEnv(w io.Writer, v Values) error {
+	bw := bufio.NewWriter(w)
+	for _, k := range v.sortedKeys() {
+		if k == "" || strings.ContainsAny(k, "=#;'\"`[") || strings.IndexFunc(k, unicode.IsSpace) != -1 {
+			return fmt.Errorf("ini: cannot write %q as a dotenv key", k)
+		}
+		for _, value := range v[k] {
+			bw.WriteString(k)
+			bw.WriteByte(rEquals)
+			bw.WriteString(quoteEnvValue(value))
+			bw.WriteByte(rNewline)
+		}
+	}
+	return bw.Flush()
+}
+
+// quoteEnvValue returns value quoted for a dotenv file, if necessary.
+func quoteEnvValue(value string) string {
+	if value == "" {
+		return value
+	}
+
+	plain := true
+	for _, r := range value {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.,:/@%+", r)) {
+			plain = false
+			break
+		}
+	}
+	switch {
+	case plain:
+		return value
+	case !strings.ContainsRune(value, rSingleQuote):
+		return string(rSingleQuote) + value + string(rSingleQuote)
+	default:
+		// quoteString never writes a $ other than those in value
+		return strings.ReplaceAll(quoteString(value), "$", `\$`)
+	}
+}
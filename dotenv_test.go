@@ -0,0 +1,142 @@
+package ini
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadDotEnv(t *testing.T) {
+	const src = `# comment
+export HOST=example.com
+PORT = 8080 # trailing comment
+URL=http://${HOST}:${PORT}/path#anchor
+EMPTY=
+COMMENTED= # nothing
+HASH=#value
+LITERAL='${HOST} \n "quoted"'
+QUOTED="line\n\t${HOST} \$HOST \x41é"
+MULTI="a
+b"
+DOLLAR=$5 and ${UNSET}
+FLAG
+export
+export=yes
+; also a comment
+`
+	want := Values{
+		"HOST":      {"example.com"},
+		"PORT":      {"8080"},
+		"URL":       {"http://example.com:8080/path#anchor"},
+		"EMPTY":     {""},
+		"COMMENTED": {""},
+		"HASH":      {"#value"},
+		"LITERAL":   {`${HOST} \n "quoted"`},
+		"QUOTED":    {"line\n\texample.com $HOST Aé"},
+		"MULTI":     {"a\nb"},
+		"DOLLAR":    {"$5 and"},
+		"FLAG":      {True},
+		"export":    {True, "yes"},
+	}
+	testReadINIMatching(t, &DotEnvDecoder, src, want)
+
+	got, err := ReadDotEnv([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDotEnv() = %v; want %v", got, want)
+	}
+}
+
+func TestReadDotEnv_getenv(t *testing.T) {
+	dec := DotEnvDecoder
+	dec.Getenv = func(name string) string {
+		return map[string]string{"HOME": "/home/user", "A": "env"}[name]
+	}
+	testReadINIMatching(t, &dec, "A=file\nB=${A}\nC=\"${HOME}/bin\"\nD=${NONE}",
+		Values{
+			"A": {"file"},
+			"B": {"file"},
+			"C": {"/home/user/bin"},
+			"D": {""},
+		})
+}
+
+func TestScanner_dotEnv(t *testing.T) {
+	toks, err := scanAll(t, &DotEnvDecoder, strings.NewReader("export A=1 2 # c\nB=\"${A}\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Token{
+		{Kind: Key, Text: "A", Raw: "A", Line: 1, Col: 8, Offset: 7},
+		{Kind: Value, Text: "1 2", Raw: "1 2", Line: 1, Col: 10, Offset: 9},
+		{Kind: Comment, Text: " c", Raw: "# c", Line: 1, Col: 14, Offset: 13},
+		{Kind: Key, Text: "B", Raw: "B", Line: 2, Col: 1, Offset: 17},
+		{Kind: Value, Text: "1 2", Raw: `"${A}"`, Line: 2, Col: 3, Offset: 19},
+	}
+	if !reflect.DeepEqual(toks, want) {
+		t.Errorf("tokens =\n%+v\nwant\n%+v", toks, want)
+	}
+}
+
+func TestReadDotEnv_errors(t *testing.T) {
+	for _, src := range []string{
+		"[section]\nA=1",
+		"A='unclosed",
+		"A=\"unclosed",
+		"A=${UNCLOSED",
+		"A=${UNCLOSED\nB=1",
+		"A=${}",
+		"A=\"${}\"",
+		"=1",
+	} {
+		err := testReadINIErrorWith(t, &DotEnvDecoder, src)
+		if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("Read(%q) error = %#v; want *SyntaxError", src, err)
+		}
+	}
+}
+
+func TestWriteDotEnv(t *testing.T) {
+	v := Values{
+		"PLAIN":  {"a-b_c.d:e/f@g%h+i,j"},
+		"SPACE":  {" x y "},
+		"QUOTE":  {"it's $HOME\n"},
+		"MULTI":  {"1", "2"},
+		"EMPTY":  {""},
+		"NONE":   nil,
+		"DOLLAR": {"${X}"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDotEnv(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	want := "DOLLAR='${X}'\n" +
+		"EMPTY=\n" +
+		"MULTI=1\n" +
+		"MULTI=2\n" +
+		"PLAIN=a-b_c.d:e/f@g%h+i,j\n" +
+		"QUOTE=\"it's \\$HOME\\n\"\n" +
+		"SPACE=' x y '\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteDotEnv() =\n%s\nwant\n%s", got, want)
+	}
+
+	got, err := ReadDotEnv(buf.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(v, "NONE")
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("ReadDotEnv(WriteDotEnv()) = %v; want %v", got, v)
+	}
+
+	for _, k := range []string{"", "A B", "A=B", "[A]", "A#"} {
+		if err := WriteDotEnv(&buf, Values{k: {"1"}}); err == nil {
+			t.Errorf("WriteDotEnv(%q) = nil; want error", k)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package ini
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of a Reader's input. Input in any encoding other than UTF-8
+// is decoded to UTF-8 before it is read, so the offsets of a Scanner's tokens are offsets in the
+// decoded input.
+type Encoding int
+
+const (
+	// DetectEncoding detects the encoding of input from its byte order mark, if any, and otherwise
+	// reads it as UTF-8 (or ISO-8859-1 if the Reader reads Properties). This is the default.
+	DetectEncoding Encoding = iota
+	// UTF8 reads input as UTF-8. A leading byte order mark is skipped.
+	UTF8
+	// UTF16LE reads input as little-endian UTF-16. A leading byte order mark is skipped.
+	UTF16LE
+	// UTF16BE reads input as big-endian UTF-16. A leading byte order mark is skipped.
+	UTF16BE
+	// Latin1 reads input as ISO-8859-1.
+	Latin1
+	// Windows1252 reads input as Windows code page 1252.
+	Windows1252
+)
+
+// Byte order marks
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// errInvalidUTF8 is returned by readRune if input is not valid UTF-8 and the decoder is strict. The
+// decoder replaces it with a *SyntaxError whose Err is ErrInvalidUTF8.
+var errInvalidUTF8 = errors.New("ini: invalid UTF-8 read")
+
+// windows1252 maps the bytes 0x80 through 0x9F of Windows-1252 to runes. Bytes undefined in
+// Windows-1252 map to the same code point, as in ISO-8859-1.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+// encoding returns the Reader's input encoding, accounting for the default.
+func (d *Reader) encoding() Encoding {
+	if d.Encoding == DetectEncoding && d.Properties {
+		return Latin1
+	}
+	return d.Encoding
+}
+
+// transcoded returns true if input in the encoding e must be decoded to UTF-8.
+func (e Encoding) transcoded() bool {
+	return e != DetectEncoding && e != UTF8
+}
+
+// readBOM skips a byte order mark at the start of input. If the decoder's encoding is detected and
+// the mark is for UTF-16, the remaining input is decoded as UTF-16. The offsets of input following
+// the mark are unchanged.
+func (d *decoder) readBOM() {
+	var w []byte
+	if d.rd != nil {
+		w, _ = d.rd.Peek(len(bomUTF8))
+	} else {
+		w = d.src
+	}
+
+	if bytes.HasPrefix(w, bomUTF8) {
+		d.consume(w[:len(bomUTF8)])
+		return
+	} else if d.encoding != DetectEncoding {
+		return
+	} else if d.encoding = detectUTF16(w); d.encoding == DetectEncoding {
+		return
+	}
+
+	d.consume(w[:2])
+	if d.rd != nil {
+		d.rd = bufio.NewReaderSize(&decodeReader{r: d.rd, enc: d.encoding}, readBufferSize)
+	} else {
+		d.src = decodeBytes(d.encoding, d.src)
+	}
+}
+
+// detectUTF16 returns the encoding of b if it begins with a UTF-16 byte order mark, and otherwise
+// returns DetectEncoding.
+func detectUTF16(b []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(b, bomUTF16LE):
+		return UTF16LE
+	case bytes.HasPrefix(b, bomUTF16BE):
+		return UTF16BE
+	default:
+		return DetectEncoding
+	}
+}
+
+// bom returns the byte order mark of e, if it is UTF-16, and otherwise nil.
+func (e Encoding) bom() []byte {
+	switch e {
+	case UTF16LE:
+		return bomUTF16LE
+	case UTF16BE:
+		return bomUTF16BE
+	default:
+		return nil
+	}
+}
+
+// invalidUTF8 returns a *SyntaxError for the invalid byte c following the current rune.
+func (d *decoder) invalidUTF8(c byte) *SyntaxError {
+	return &SyntaxError{
+		File: d.file,
+		Line: d.line,
+		Col:  d.col + 1,
+		Err:  ErrInvalidUTF8,
+		Desc: fmt.Sprintf("invalid byte %#02x", c),
+	}
+}
+
+// decodeReader is an io.Reader that decodes input from r in the encoding enc to UTF-8. A byte
+// order mark at the start of UTF-16 input is skipped.
+type decodeReader struct {
+	r       io.Reader
+	enc     Encoding
+	buf     []byte // buf holds input that has not been decoded
+	out     []byte // out holds decoded input that has not been read
+	err     error
+	started bool
+}
+
+func (t *decodeReader) Read(p []byte) (int, error) {
+	for len(t.out) == 0 {
+		if t.err != nil {
+			return 0, t.err
+		}
+
+		if t.buf == nil {
+			t.buf = make([]byte, 0, 4096)
+		}
+		n, err := t.r.Read(t.buf[len(t.buf):cap(t.buf)])
+		t.buf = t.buf[:len(t.buf)+n]
+		t.err = err
+
+		if !t.started && (len(t.buf) >= 2 || err != nil) {
+			t.started = true
+			if t.enc == UTF16LE && bytes.HasPrefix(t.buf, bomUTF16LE) ||
+				t.enc == UTF16BE && bytes.HasPrefix(t.buf, bomUTF16BE) {
+				t.buf = t.buf[:copy(t.buf, t.buf[2:])]
+			}
+		} else if !t.started {
+			continue
+		}
+
+		var rest []byte
+		t.out, rest = decodeTo(t.out[:0], t.enc, t.buf, err != nil)
+		t.buf = t.buf[:copy(t.buf, rest)]
+	}
+
+	n := copy(p, t.out)
+	t.out = t.out[n:]
+	return n, nil
+}
+
+// decodeBytes returns b, in the encoding enc, decoded to UTF-8.
+func decodeBytes(enc Encoding, b []byte) []byte {
+	if bom := enc.bom(); bom != nil {
+		b = bytes.TrimPrefix(b, bom)
+	}
+	out, _ := decodeTo(make([]byte, 0, len(b)+len(b)/2), enc, b, true)
+	return out
+}
+
+// decodeTo appends the input b, in the encoding enc, to out as UTF-8 and returns the result and any
+// input that ends in an incomplete character. If eof is true, an incomplete character at the end
+// of b is decoded as utf8.RuneError.
+func decodeTo(out []byte, enc Encoding, b []byte, eof bool) (_, rest []byte) {
+	switch enc {
+	case Latin1, Windows1252:
+		for _, c := range b {
+			r := rune(c)
+			if enc == Windows1252 && c >= 0x80 && c < 0xA0 {
+				r = windows1252[c-0x80]
+			}
+			out = utf8.AppendRune(out, r)
+		}
+		return out, nil
+	case UTF16LE, UTF16BE:
+		for len(b) >= 2 {
+			r := utf16Unit(enc, b)
+			if !utf16.IsSurrogate(r) {
+				out, b = utf8.AppendRune(out, r), b[2:]
+				continue
+			} else if len(b) < 4 && !eof {
+				return out, b
+			}
+
+			if len(b) >= 4 {
+				if pair := utf16.DecodeRune(r, utf16Unit(enc, b[2:])); pair != utf8.RuneError {
+					out, b = utf8.AppendRune(out, pair), b[4:]
+					continue
+				}
+			}
+			out, b = utf8.AppendRune(out, utf8.RuneError), b[2:]
+		}
+		if len(b) > 0 && eof {
+			out, b = utf8.AppendRune(out, utf8.RuneError), nil
+		}
+		return out, b
+	default:
+		return append(out, b...), nil
+	}
+}
+
+// utf16Unit returns the UTF-16 code unit at the start of b.
+func utf16Unit(enc Encoding, b []byte) rune {
+	if enc == UTF16LE {
+		return rune(b[0]) | rune(b[1])<<8
+	}
+	return rune(b[0])<<8 | rune(b[1])
+}
+
+// encodeBytes returns b, in UTF-8, encoded in the encoding enc. It returns an error if b contains
+// a rune that cannot be written in enc.
+func encodeBytes(enc Encoding, b []byte) ([]byte, error) {
+	if !enc.transcoded() {
+		return b, nil
+	}
+
+	out := make([]byte, 0, len(b))
+	for _, r := range string(b) {
+		switch enc {
+		case UTF16LE, UTF16BE:
+			for _, u := range utf16.AppendRune(nil, r) {
+				if enc == UTF16LE {
+					out = append(out, byte(u), byte(u>>8))
+				} else {
+					out = append(out, byte(u>>8), byte(u))
+				}
+			}
+		default:
+			c, ok := encodeByte(enc, r)
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrUnencodable, r)
+			}
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// encodeByte returns the byte that r is written as in enc, which is either Latin1 or Windows1252,
+// and whether there is one.
+func encodeByte(enc Encoding, r rune) (byte, bool) {
+	if enc == Windows1252 && r >= 0x80 {
+		for i, w := range windows1252 {
+			if w == r {
+				return byte(0x80 + i), true
+			}
+		}
+		if r < 0xA0 {
+			return 0, false
+		}
+	}
+	return byte(r), r <= 0xFF
+}
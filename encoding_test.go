@@ -0,0 +1,142 @@
+package ini
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+// encodeUTF16 returns s encoded as UTF-16, with a byte order mark if bom is true.
+func encodeUTF16(s string, bigEndian, bom bool) string {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	b := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		if bigEndian {
+			b = append(b, byte(u>>8), byte(u))
+		} else {
+			b = append(b, byte(u), byte(u>>8))
+		}
+	}
+	return string(b)
+}
+
+func TestReader_encoding(t *testing.T) {
+	const src = "[sect]\nkey = café 😀\n"
+	want := Values{"sect.key": {"café 😀"}}
+
+	cases := []struct {
+		name string
+		enc  Encoding
+		src  string
+	}{
+		{"UTF-8 BOM", DetectEncoding, "\xEF\xBB\xBF" + src},
+		{"UTF-8 BOM explicit", UTF8, "\xEF\xBB\xBF" + src},
+		{"UTF-16LE BOM", DetectEncoding, encodeUTF16(src, false, true)},
+		{"UTF-16BE BOM", DetectEncoding, encodeUTF16(src, true, true)},
+		{"UTF-16LE", UTF16LE, encodeUTF16(src, false, false)},
+		{"UTF-16BE explicit BOM", UTF16BE, encodeUTF16(src, true, true)},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			dec := Reader{Casing: CaseSensitive, Encoding: c.enc}
+			testReadINIMatching(t, &dec, c.src, want)
+
+			got := Values{}
+			if err := dec.readBytes([]byte(c.src), got); err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(got, want) {
+				t.Errorf("readBytes() = %q; want %q", got, want)
+			}
+		})
+	}
+
+	dec := Reader{Encoding: Latin1}
+	testReadINIMatching(t, &dec, "k = caf\xe9 \x80", Values{"k": {"café \u0080"}})
+	dec.Encoding = Windows1252
+	testReadINIMatching(t, &dec, "k = caf\xe9 \x80\x93\x9d", Values{"k": {"café €“\u009d"}})
+
+	// Without a BOM, UTF-16 is not detected and a UTF-16 BOM is not skipped if the encoding is set
+	dec.Encoding = UTF8
+	testReadINIMatching(t, &dec, "\xFF\xFEk", Values{"��k": {True}})
+}
+
+func TestScanner_bomOffsets(t *testing.T) {
+	toks, err := scanAll(t, &DefaultDecoder, strings.NewReader("\xEF\xBB\xBFk = v\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Token{
+		{Kind: Key, Text: "k", Raw: "k", Line: 1, Col: 1, Offset: 3},
+		{Kind: Value, Text: "v", Raw: "v", Line: 1, Col: 5, Offset: 7},
+	}
+	if !reflect.DeepEqual(toks, want) {
+		t.Errorf("tokens =\n%#v\nwant\n%#v", toks, want)
+	}
+}
+
+func TestReader_strictUTF8(t *testing.T) {
+	dec := Reader{StrictUTF8: true}
+	testReadINIMatching(t, &dec, "k = café", Values{"k": {"café"}})
+
+	for _, c := range []struct {
+		src       string
+		line, col int
+	}{
+		{"k = a\xffb", 1, 6},
+		{"k = 1\n\xc3", 2, 1},
+		{"k = \"\xe2\x82\"", 1, 6},
+		{"[s\xff]", 1, 3},
+	} {
+		for _, read := range []func() error{
+			func() error { return dec.Read(plainReader{strings.NewReader(c.src)}, Values{}) },
+			func() error { return dec.readBytes([]byte(c.src), Values{}) },
+		} {
+			var se *SyntaxError
+			if err := read(); !errors.As(err, &se) || se.Err != ErrInvalidUTF8 {
+				t.Errorf("Read(%q) = %v; want ErrInvalidUTF8", c.src, err)
+			} else if se.Line != c.line || se.Col != c.col {
+				t.Errorf("Read(%q) = %v; want error at %d:%d", c.src, err, c.line, c.col)
+			}
+		}
+	}
+
+	// Without StrictUTF8, invalid bytes are read as utf8.RuneError
+	testReadINIMatching(t, &Reader{}, "k = a\xffb", Values{"k": {"a�b"}})
+}
+
+func TestDecodeReader(t *testing.T) {
+	cases := []struct {
+		enc  Encoding
+		src  string
+		want string
+	}{
+		{Latin1, "a\xe9b\xff", "aébÿ"},
+		{Windows1252, "\x80\x9f", "€Ÿ"},
+		{UTF16LE, encodeUTF16("a😀b", false, true), "a😀b"},
+		{UTF16BE, encodeUTF16("a😀b", true, false), "a😀b"},
+		{UTF16LE, "\x3d\xd8a\x00", "�a"}, // unpaired surrogate
+		{UTF16LE, "a\x00b", "a�"},        // odd length
+	}
+	for _, c := range cases {
+		// Input and output are split across reads of a single byte
+		r := &decodeReader{r: iotest.OneByteReader(strings.NewReader(c.src)), enc: c.enc}
+		got, err := io.ReadAll(iotest.OneByteReader(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != c.want {
+			t.Errorf("decodeReader(%v, %q) = %q; want %q", c.enc, c.src, got, c.want)
+		}
+		if got := decodeBytes(c.enc, []byte(c.src)); string(got) != c.want {
+			t.Errorf("decodeBytes(%v, %q) = %q; want %q", c.enc, c.src, got, c.want)
+		}
+	}
+}
@@ -7,18 +7,25 @@ import (
 
 // SyntaxError is an error returned when the INI parser encounters any syntax it does not
 // understand. It contains the line, column, any other error encountered, and a description of the
-// syntax error.
+// syntax error. If the input was read from a file, as by ReadFile, File is the name of the file.
 type SyntaxError struct {
+	File      string
 	Line, Col int
 	Err       error
 	Desc      string
 }
 
 func (s *SyntaxError) Error() string {
+	loc := Location{File: s.File, Line: s.Line, Col: s.Col}
 	if s.Desc == "" {
-		return fmt.Sprintf("ini: syntax error at %d:%d: %v", s.Line, s.Col, s.Err)
+		return fmt.Sprintf("ini: syntax error at %v: %v", loc, s.Err)
 	}
-	return fmt.Sprintf("ini: syntax error at %d:%d: %v -- %s", s.Line, s.Col, s.Err, s.Desc)
+	return fmt.Sprintf("ini: syntax error at %v: %v -- %s", loc, s.Err, s.Desc)
+}
+
+// Unwrap returns the underlying error of the SyntaxError.
+func (s *SyntaxError) Unwrap() error {
+	return s.Err
 }
 
 // UnclosedError is an error describing an unclosed bracket from {, (, [, and <. It is typically set
@@ -61,9 +68,131 @@ var (
 	ErrSectionRawStr = errors.New("ini: raw string not accepted in section")
 	// ErrUnclosedSection is a syntax error seen when a section name has not been closed.
 	ErrUnclosedSection = errors.New("ini: section missing closing ]")
+	// ErrUnclosedHeredoc is a syntax error seen when input ends before the terminator of a
+	// heredoc value.
+	ErrUnclosedHeredoc = errors.New("ini: unclosed heredoc")
 	// ErrEmptyKey is a syntax error seen if a key is empty.
 	ErrEmptyKey = errors.New("ini: key is empty")
+	// ErrInvalidKey is returned when a key cannot be written to a Document, such as when it
+	// contains whitespace or would be cased differently by the Document's Reader.
+	ErrInvalidKey = errors.New("ini: key cannot be written to document")
+	// ErrInvalidValue is returned when a value written to a Document would not be read back as the
+	// same value.
+	ErrInvalidValue = errors.New("ini: value cannot be written to document")
+	// ErrUnencodable is returned when a Document is edited to hold a character that cannot be
+	// written in the encoding of its input, such as "€" in ISO-8859-1.
+	ErrUnencodable = errors.New("ini: character cannot be written in document encoding")
+
+	// ErrInvalidUTF8 is a syntax error seen when input is not valid UTF-8 and a Reader's StrictUTF8
+	// option is set.
+	ErrInvalidUTF8 = errors.New("ini: invalid UTF-8")
 
 	// ErrBadNewline is a BadCharError for unexpected newlines.
 	ErrBadNewline = BadCharError('\n')
 )
+
+// InheritanceError is an error returned when section inheritance cannot be resolved. Section is the
+// section whose parents could not be resolved, Parent is the parent section being resolved when the
+// error occurred, and Err describes the problem (typically ErrInheritanceCycle or ErrNoParent).
+type InheritanceError struct {
+	Section, Parent string
+	Err             error
+}
+
+func (e *InheritanceError) Error() string {
+	return fmt.Sprintf("ini: section %q cannot inherit from %q: %v", e.Section, e.Parent, e.Err)
+}
+
+// Unwrap returns the underlying error of the InheritanceError.
+func (e *InheritanceError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// ErrInheritanceCycle is an inheritance error seen when a section inherits from itself,
+	// directly or through its parents.
+	ErrInheritanceCycle = errors.New("ini: section inheritance cycle")
+	// ErrNoParent is an inheritance error seen when a parent section is not defined or the
+	// parent section name is not a valid section heading.
+	ErrNoParent = errors.New("ini: parent section not found")
+)
+
+// RecorderError is an error returned when a Recorder panics while adding a value. Key and Value are
+// the key and value being added, and Panic is the value that the Recorder panicked with. If the
+// value is secret to the Reader's Redactor, Value is Redacted.
+type RecorderError struct {
+	Key, Value string
+	Panic      interface{}
+}
+
+func (e *RecorderError) Error() string {
+	return fmt.Sprintf("ini: recorder panicked adding %q: %v", e.Key, e.Panic)
+}
+
+// Unwrap returns the value the Recorder panicked with if it is an error.
+func (e *RecorderError) Unwrap() error {
+	err, _ := e.Panic.(error)
+	return err
+}
+
+// LimitError is an error returned when input exceeds one of a Reader's limits. Limit is the name of
+// the Reader field for the limit that was exceeded (e.g., "MaxKeys"), Max is the value of that
+// limit, and Line and Col are the position in the input where it was exceeded.
+type LimitError struct {
+	Limit     string
+	Max       int64
+	Line, Col int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("ini: %s of %d exceeded at %d:%d", e.Limit, e.Max, e.Line, e.Col)
+}
+
+// SecretError is an error returned when a Reader's Resolver cannot resolve a reference to a secret.
+// Key is the key whose value is the reference, and Err is the error returned by the Resolver. To
+// keep secrets out of logs, the reference itself is not included.
+type SecretError struct {
+	Key string
+	Err error
+}
+
+func (e *SecretError) Error() string {
+	return fmt.Sprintf("ini: resolving secret of %q: %v", e.Key, e.Err)
+}
+
+// Unwrap returns the error returned by the Resolver.
+func (e *SecretError) Unwrap() error {
+	return e.Err
+}
+
+// ErrSecretNotFound is returned by a SecretResolver when a reference it handles does not refer to
+// a secret.
+var ErrSecretNotFound = errors.New("ini: secret not found")
+
+// TemplateError is an error returned by Render when a template cannot be rendered. Line and Col
+// are the position in the template of the heading, key, value, or directive that could not be
+// rendered, and Err describes the problem.
+type TemplateError struct {
+	Line, Col int
+	Err       error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("ini: template error at %d:%d: %v", e.Line, e.Col, e.Err)
+}
+
+// Unwrap returns the underlying error of the TemplateError.
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// ErrUndefinedVar is a template error seen when a placeholder names a variable that is not
+	// defined.
+	ErrUndefinedVar = errors.New("ini: undefined template variable")
+	// ErrBadDirective is a template error seen when a directive is malformed or misplaced.
+	ErrBadDirective = errors.New("ini: invalid template directive")
+)
+
+// ErrInvalidQuery is returned by CompileQuery when a query pattern is malformed.
+var ErrInvalidQuery = errors.New("ini: invalid query")
@@ -0,0 +1,273 @@
+package ini
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// FormatStyle controls how Format writes INI input.
+type FormatStyle struct {
+	// Reader is the Reader used to parse input. If nil, DefaultDecoder is used.
+	Reader *Reader
+	// Indent is written before each key and comment in a section. Keys before the first section
+	// are never indented.
+	Indent string
+	// Equals is written between a key and its value. If Equals is the empty string, it defaults to
+	// " = ".
+	Equals string
+	// SectionGap is the number of blank lines written before each section heading. Runs of blank
+	// lines elsewhere are collapsed into a single blank line.
+	SectionGap int
+	// Comment is the character that begins comments. If Comment is zero, comments keep the
+	// character they were written with. Otherwise, it must be ';' or '#'.
+	Comment byte
+	// MinimalQuotes rewrites values so that they are only quoted when needed, as Reader's dialect
+	// quotes them: escaped for a properties file, quoted as by WriteDotEnv for a dotenv file, and
+	// quoted as INI otherwise. Heredoc values are never rewritten.
+	MinimalQuotes bool
+	// SortKeys sorts keys within each section. Keys that occur more than once keep the order of
+	// their values.
+	SortKeys bool
+	// SortSections sorts sections by name. Keys before the first section are always written
+	// first.
+	SortSections bool
+}
+
+// DefaultStyle is the FormatStyle used by the ini command's fmt command.
+var DefaultStyle = FormatStyle{
+	Equals:        " = ",
+	SectionGap:    1,
+	MinimalQuotes: true,
+}
+
+// fmtEntry is a key or section heading and the comments and blank lines attached to it.
+type fmtEntry struct {
+	leading  []Token // leading are the comments and blank lines preceding the entry
+	tok      Token   // tok is the key or section heading
+	value    *Token
+	trailing *Token // trailing is a comment on the same line as the entry
+}
+
+// fmtSection is a section and its keys. The first section of a file has no heading.
+type fmtSection struct {
+	fmtEntry
+	keys []fmtEntry
+	tail []Token // tail are the comments following the last key of the section
+}
+
+// Format reformats the INI input src according to style and returns the result. Comments are kept
+// with the key or section heading that they precede or follow on the same line, so they move with
+// them if keys or sections are sorted. Comments at the start of src that are followed by a blank
+// line are kept at the start of the result. Format returns an error if src cannot be parsed.
+func Format(src []byte, style FormatStyle) ([]byte, error) {
+	dec := style.Reader
+	if dec == nil {
+		dec = &DefaultDecoder
+	}
+
+	header, sections, err := parseFormat(dec, src)
+	if err != nil {
+		return nil, err
+	}
+
+	if style.SortSections {
+		rest := sections[1:]
+		sort.SliceStable(rest, func(i, j int) bool { return rest[i].tok.Text < rest[j].tok.Text })
+	}
+	if style.SortKeys {
+		for i := range sections {
+			keys := sections[i].keys
+			sort.SliceStable(keys, func(i, j int) bool { return keys[i].tok.Text < keys[j].tok.Text })
+		}
+	}
+
+	f := formatter{style: style, dec: dec}
+	if f.style.Equals == "" {
+		f.style.Equals = " = "
+	}
+	f.lines(header, "")
+	f.blank = len(header) > 0
+	for i := range sections {
+		f.section(&sections[i], i > 0)
+	}
+	return f.buf.Bytes(), nil
+}
+
+// parseFormat scans src and returns the comments at its start and its sections.
+func parseFormat(dec *Reader, src []byte) (header []Token, sections []fmtSection, err error) {
+	sections = []fmtSection{{}}
+	var (
+		pending []Token   // comments and blank lines not yet attached
+		last    *fmtEntry // last is the most recent entry, for trailing comments
+		line    int       // line is the line the last token ended on
+	)
+
+	s := dec.NewScanner(bytes.NewReader(src))
+	for s.Next() {
+		tok := s.Token()
+		sameLine := tok.Line == line && last != nil
+
+		switch tok.Kind {
+		case Blank:
+			// Collapse runs of blank lines
+			if n := len(pending); n == 0 || pending[n-1].Kind != Blank {
+				pending = append(pending, tok)
+			}
+		case Comment:
+			if sameLine && last.trailing == nil {
+				c := tok
+				last.trailing = &c
+			} else {
+				pending = append(pending, tok)
+			}
+		case SectionStart:
+			sections = append(sections, fmtSection{fmtEntry: fmtEntry{leading: pending, tok: tok}})
+			pending = nil
+			last = &sections[len(sections)-1].fmtEntry
+		case Key:
+			sect := &sections[len(sections)-1]
+			sect.keys = append(sect.keys, fmtEntry{leading: pending, tok: tok})
+			pending = nil
+			last = &sect.keys[len(sect.keys)-1]
+		case Value:
+			v := tok
+			last.value = &v
+		}
+		line = tok.Line + strings.Count(tok.Raw, "\n")
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, err
+	}
+	sections[len(sections)-1].tail = pending
+
+	// Detach comments at the start of the input, if they are followed by a blank line
+	var first *fmtEntry
+	switch {
+	case len(sections[0].keys) > 0:
+		first = &sections[0].keys[0]
+	case len(sections) > 1:
+		first = &sections[1].fmtEntry
+	default:
+		header, sections[0].tail = sections[0].tail, nil
+		return header, sections, nil
+	}
+	for i := len(first.leading) - 1; i >= 0; i-- {
+		if first.leading[i].Kind == Blank {
+			header, first.leading = first.leading[:i], first.leading[i+1:]
+			break
+		}
+	}
+	return header, sections, nil
+}
+
+// formatter writes formatted INI to a buffer.
+type formatter struct {
+	style FormatStyle
+	dec   *Reader
+	buf   bytes.Buffer
+	blank bool // blank is true if a blank line should be written before the next line
+}
+
+// newline writes a newline, preceded by a blank line if one is pending.
+func (f *formatter) newline() {
+	if f.blank && f.buf.Len() > 0 {
+		f.buf.WriteByte('\n')
+	}
+	f.blank = false
+}
+
+// lines writes comments and blank lines, indenting comments by indent.
+func (f *formatter) lines(toks []Token, indent string) {
+	for _, tok := range toks {
+		if tok.Kind == Blank {
+			f.blank = true
+			continue
+		}
+		f.newline()
+		f.buf.WriteString(indent)
+		f.comment(tok)
+		f.buf.WriteByte('\n')
+	}
+}
+
+// comment writes the comment tok.
+func (f *formatter) comment(tok Token) {
+	raw := tok.Raw
+	if f.style.Comment != 0 {
+		raw = string(f.style.Comment) + raw[1:]
+	}
+	f.buf.WriteString(strings.TrimRight(raw, " \t\r"))
+}
+
+// section writes the section s and its keys, including its heading if heading is true.
+func (f *formatter) section(s *fmtSection, heading bool) {
+	indent := ""
+	if heading {
+		leading := trimBlank(s.leading)
+		f.blank = false
+		if f.buf.Len() > 0 {
+			for i := 0; i < f.style.SectionGap; i++ {
+				f.buf.WriteByte('\n')
+			}
+		}
+		f.lines(leading, "")
+		f.buf.WriteString(s.tok.Raw)
+		f.trailing(s.trailing)
+		indent = f.style.Indent
+	}
+
+	for i := range s.keys {
+		k := &s.keys[i]
+		leading := k.leading
+		if i == 0 && heading || f.style.SortKeys {
+			leading = trimBlank(leading)
+		}
+		f.lines(leading, indent)
+		f.newline()
+		f.buf.WriteString(indent)
+		f.buf.WriteString(k.tok.Raw)
+		if k.value != nil {
+			f.value(k.value)
+		}
+		f.trailing(k.trailing)
+	}
+	f.lines(s.tail, indent)
+}
+
+// value writes the equals sign and value of a key.
+func (f *formatter) value(tok *Token) {
+	raw := tok.Raw
+	if f.style.MinimalQuotes && !(f.dec.Heredoc && strings.HasPrefix(raw, "<<")) {
+		raw = tok.Text
+		if raw != "" {
+			raw = f.dec.formatValue(raw)
+		}
+	}
+	if raw == "" {
+		f.buf.WriteString(strings.TrimRight(f.style.Equals, " \t"))
+		return
+	}
+	f.buf.WriteString(f.style.Equals)
+	f.buf.WriteString(raw)
+}
+
+// trailing writes the comment tok, if any, and ends the line.
+func (f *formatter) trailing(tok *Token) {
+	if tok != nil {
+		f.buf.WriteByte(' ')
+		f.comment(*tok)
+	}
+	f.buf.WriteByte('\n')
+}
+
+// trimBlank returns toks without leading or trailing blank lines.
+func trimBlank(toks []Token) []Token {
+	for len(toks) > 0 && toks[0].Kind == Blank {
+		toks = toks[1:]
+	}
+	for len(toks) > 0 && toks[len(toks)-1].Kind == Blank {
+		toks = toks[:len(toks)-1]
+	}
+	return toks
+}
@@ -0,0 +1,129 @@
+package ini
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testFormat(t *testing.T, src string, style FormatStyle, want string) {
+	t.Helper()
+	got, err := Format([]byte(src), style)
+	if err != nil {
+		t.Fatalf("Format(%q) = %v", src, err)
+	}
+	if string(got) != want {
+		t.Errorf("Format(%q) =\n%s\nwant\n%s", src, got, want)
+	}
+
+	// Formatting must not change values, except for the order of keys
+	dec := style.Reader
+	if dec == nil {
+		dec = &DefaultDecoder
+	}
+	before, after := Values{}, Values{}
+	if err := dec.readBytes([]byte(src), before); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.readBytes(got, after); err != nil {
+		t.Fatalf("formatted output does not parse: %v", err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("values changed by Format: %v; want %v", after, before)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	const src = "; file header\n\n\n" +
+		"top=1   \n" +
+		"[b]   ; heading comment\n" +
+		"   z   =    `raw`\n" +
+		"\n\n\n" +
+		"  ; about y\n" +
+		"y=\"quoted\"#trailing\n" +
+		"flag\n" +
+		"e =\n" +
+		"; about a\n" +
+		"[a] k = \" padded\"\n"
+
+	testFormat(t, src, DefaultStyle, "; file header\n"+
+		"\n"+
+		"top = 1\n"+
+		"\n"+
+		"[b] ; heading comment\n"+
+		"z = raw\n"+
+		"\n"+
+		"; about y\n"+
+		"y = quoted #trailing\n"+
+		"flag\n"+
+		"e =\n"+
+		"\n"+
+		"; about a\n"+
+		"[a]\n"+
+		"k = \" padded\"\n")
+
+	style := FormatStyle{
+		Indent:       "\t",
+		Equals:       "=",
+		SectionGap:   2,
+		Comment:      '#',
+		SortKeys:     true,
+		SortSections: true,
+	}
+	testFormat(t, src, style, "# file header\n"+
+		"\n"+
+		"top=1\n"+
+		"\n"+
+		"\n"+
+		"# about a\n"+
+		"[a]\n"+
+		"\tk=\" padded\"\n"+
+		"\n"+
+		"\n"+
+		"[b] # heading comment\n"+
+		"\te=\n"+
+		"\tflag\n"+
+		"\t# about y\n"+
+		"\ty=\"quoted\" #trailing\n"+
+		"\tz=`raw`\n")
+}
+
+func TestFormat_multiValue(t *testing.T) {
+	dec := Reader{Heredoc: true}
+	style := DefaultStyle
+	style.Reader, style.SortKeys = &dec, true
+	testFormat(t, "b = 2\na = 1\nb = 1\nh = <<-EOF\n  x\n  EOF\n", style,
+		"a = 1\nb = 2\nb = 1\nh = <<-EOF\n  x\n  EOF\n")
+}
+
+func TestFormat_dialects(t *testing.T) {
+	style := DefaultStyle
+	style.Reader = &PropertiesDecoder
+	testFormat(t, "a=x;y\nb:\\ c\\\n  d\n# note\ne=\"q\"\n", style,
+		"a = x;y\nb = \\ cd\n# note\ne = \"q\"\n")
+
+	style.Reader = &Reader{DotEnv: true, Casing: CaseSensitive}
+	testFormat(t, "export A=\"x y\"\nB='$z'\nC=\"a'b\\$c\"\nD=plain\n", style,
+		"A = 'x y'\nB = '$z'\nC = \"a'b\\$c\"\nD = plain\n")
+
+	style.Reader = &Reader{ArrayKeys: true}
+	testFormat(t, "k[]=\"a\"\nk[x]=b;c\n", style, "k[] = a\nk[x] = b ;c\n")
+
+	// Without Heredoc, a value beginning with << is an ordinary value
+	style.Reader = nil
+	testFormat(t, "k=\"<<EOF\"\n", style, "k = \"<<EOF\"\n")
+}
+
+func TestFormat_invalidUTF8(t *testing.T) {
+	testFormat(t, "k = \"a\\x80b\"\n", DefaultStyle, "k = \"a\\x80b\"\n")
+}
+
+func TestFormat_empty(t *testing.T) {
+	testFormat(t, "", DefaultStyle, "")
+	testFormat(t, "\n\n; only a comment\n\n", DefaultStyle, "; only a comment\n")
+}
+
+func TestFormat_error(t *testing.T) {
+	if _, err := Format([]byte("k = \"open"), DefaultStyle); err == nil {
+		t.Error("Format() = nil; want error")
+	}
+}
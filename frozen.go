@@ -0,0 +1,151 @@
+package ini
+
+import (
+	"iter"
+	"sort"
+	"strings"
+)
+
+// Frozen is a read-only snapshot of Values. Because it cannot be modified, a Frozen may be shared
+// and read by any number of goroutines without locking. Accessors that return slices return
+// copies of them.
+//
+// A Frozen may also be a view of a single section of another Frozen, returned by Section. Keys in
+// a section view are relative to the section, and the view shares the snapshot of its parent
+// rather than copying it.
+//
+// The zero value of a Frozen is an empty snapshot.
+type Frozen struct {
+	values Values
+	prefix string // prefix is the section prefix, including its separator, of a section view
+	sep    string // sep is the separator between sections and keys, as passed to FreezeSep
+}
+
+// Freeze returns a read-only snapshot of the receiver. The receiver's keys and values are copied,
+// so later changes to the receiver are not visible through the snapshot. Sections of the snapshot
+// use the default separator, "." (period).
+func (v Values) Freeze() *Frozen {
+	return v.FreezeSep("")
+}
+
+// FreezeSep returns a read-only snapshot of the receiver, as Freeze does, whose sections are
+// separated from their keys by sep. As with Reader.Separator, sep defaults to "." (period) if it is
+// the empty string, and keys have no sections if it is None.
+func (v Values) FreezeSep(sep string) *Frozen {
+	n := 0
+	for _, vs := range v {
+		n += len(vs)
+	}
+
+	// Copy all values into a single slice, shared by the snapshot's keys
+	all := make([]string, 0, n)
+	values := make(Values, len(v))
+	for k, vs := range v {
+		i := len(all)
+		all = append(all, vs...)
+		values[k] = all[i:len(all):len(all)]
+	}
+	return &Frozen{values: values, sep: sep}
+}
+
+// Section returns a view of the keys in the section with the given name and its subsections. The
+// keys of the view are relative to the section, so the key "a.b.c" is "c" in the section "a.b",
+// and "b.c" in the section "a", using the separator the receiver was frozen with. If the separator
+// is None, keys have no sections and Section returns an empty view.
+func (f *Frozen) Section(name string) *Frozen {
+	sep := separatorOrDefault(f.sep)
+	if sep == "" {
+		return &Frozen{sep: f.sep}
+	}
+	return &Frozen{values: f.values, prefix: f.prefix + name + sep, sep: f.sep}
+}
+
+// Lookup returns a copy of the values for key and whether key is defined.
+func (f *Frozen) Lookup(key string) ([]string, bool) {
+	vs, ok := f.values[f.prefix+key]
+	if !ok {
+		return nil, false
+	}
+	return append([]string(nil), vs...), true
+}
+
+// Get returns the first value for key. If key does not exist or has no values, Get returns an
+// empty string.
+func (f *Frozen) Get(key string) string {
+	if vs := f.values[f.prefix+key]; len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// Contains returns true if key is defined. As with Values.Contains, key may have no values.
+func (f *Frozen) Contains(key string) bool {
+	_, ok := f.values[f.prefix+key]
+	return ok
+}
+
+// Keys returns an iterator over the keys of the receiver in sorted order, as Values.Keys does.
+func (f *Frozen) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, k := range f.sortedKeys() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of the receiver in sorted order.
+func (f *Frozen) sortedKeys() []string {
+	keys := make([]string, 0, len(f.values))
+	for k := range f.values {
+		if strings.HasPrefix(k, f.prefix) {
+			keys = append(keys, k[len(f.prefix):])
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Len returns the number of keys in the receiver.
+func (f *Frozen) Len() int {
+	if f.prefix == "" {
+		return len(f.values)
+	}
+	n := 0
+	for k := range f.values {
+		if strings.HasPrefix(k, f.prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// All returns an iterator over every key and value in the receiver, in the same order as
+// Values.All.
+func (f *Frozen) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, k := range f.sortedKeys() {
+			for _, value := range f.values[f.prefix+k] {
+				if !yield(k, value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Thaw returns a modifiable copy of the receiver's keys and values. Keys of a section view are
+// relative to its section.
+func (f *Frozen) Thaw() Values {
+	if f.prefix == "" {
+		return f.values.Copy(nil)
+	}
+	dst := make(Values)
+	for k, vs := range f.values {
+		if strings.HasPrefix(k, f.prefix) {
+			dst[k[len(f.prefix):]] = append([]string(nil), vs...)
+		}
+	}
+	return dst
+}
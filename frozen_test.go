@@ -0,0 +1,82 @@
+package ini
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestFrozen(t *testing.T) {
+	v := Values{"a": {"1", "2"}, "s.b": {"3"}, "s.t.c": {"4"}, "s.e": nil}
+	f := v.Freeze()
+	v.Set("a", "changed")
+	v["s.b"][0] = "changed"
+
+	if got, ok := f.Lookup("a"); !ok || !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("Lookup(a) = %q, %t; want [1 2], true", got, ok)
+	} else {
+		got[0] = "modified"
+	}
+	if got := f.Get("a"); got != "1" {
+		t.Errorf("Get(a) = %q; want 1", got)
+	}
+	if got := f.Get("s.b"); got != "3" {
+		t.Errorf("Get(s.b) = %q; want 3", got)
+	}
+	if !f.Contains("s.e") || f.Contains("e") {
+		t.Error("Contains(s.e) = false or Contains(e) = true")
+	}
+	if got, want := slices.Collect(f.Keys()), []string{"a", "s.b", "s.e", "s.t.c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %q; want %q", got, want)
+	}
+
+	thawed := f.Thaw()
+	thawed.Add("a", "3")
+	if got := len(f.values["a"]); got != 2 {
+		t.Errorf("len(a) after Thaw().Add = %d; want 2", got)
+	}
+}
+
+func TestFrozen_Section(t *testing.T) {
+	f := Values{"a": {"1"}, "s.b": {"2"}, "s.t.c": {"3"}, "sx.d": {"4"}}.Freeze()
+	s := f.Section("s")
+	if got, want := slices.Collect(s.Keys()), []string{"b", "t.c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %q; want %q", got, want)
+	}
+	if s.Len() != 2 || f.Len() != 4 {
+		t.Errorf("Len() = %d, %d; want 2, 4", s.Len(), f.Len())
+	}
+	if got := s.Section("t").Get("c"); got != "3" {
+		t.Errorf("Section(t).Get(c) = %q; want 3", got)
+	}
+	if got, want := s.Thaw(), (Values{"b": {"2"}, "t.c": {"3"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Thaw() = %v; want %v", got, want)
+	}
+
+	var all []string
+	for k, v := range s.All() {
+		all = append(all, k+"="+v)
+	}
+	if want := []string{"b=2", "t.c=3"}; !reflect.DeepEqual(all, want) {
+		t.Errorf("All() = %q; want %q", all, want)
+	}
+
+	var zero Frozen
+	if zero.Len() != 0 || zero.Get("a") != "" {
+		t.Error("zero Frozen is not empty")
+	}
+}
+
+func TestFrozen_SectionSep(t *testing.T) {
+	v := Values{"s.x": {"1"}, "s:b": {"2"}, "s:t:c": {"3"}}
+	f := v.FreezeSep(":")
+	if got, want := slices.Collect(f.Section("s").Keys()), []string{"b", "t:c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Section(s).Keys() = %q; want %q", got, want)
+	}
+	if got := f.Section("s").Section("t").Get("c"); got != "3" {
+		t.Errorf("Section(s).Section(t).Get(c) = %q; want 3", got)
+	}
+	if got := v.FreezeSep(None).Section("s").Len(); got != 0 {
+		t.Errorf("FreezeSep(None).Section(s).Len() = %d; want 0", got)
+	}
+}
@@ -1 +1,3 @@
 module go.spiff.io/go-ini
+
+go 1.23
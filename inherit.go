@@ -0,0 +1,200 @@
+package ini
+
+import (
+	"sort"
+	"strings"
+)
+
+// countRecorder is a Recorder that counts the number of values added to it.
+type countRecorder int
+
+func (c *countRecorder) Add(string, string) { *c++ }
+
+// sectionPrefix returns the key prefix that the section heading name would produce when read by
+// the receiver, including its trailing separator. For example, with the default separator,
+// "backend a" produces the prefix "backend.a.".
+func (d *Reader) sectionPrefix(name string) (string, error) {
+	var (
+		dec decoder
+		n   countRecorder
+	)
+	dec.resetBytes(nil, d, &n, []byte("["+name+"]"))
+	if err := dec.read(); err != nil {
+		return "", err
+	} else if n > 0 || len(dec.prefix) == 0 {
+		return "", ErrNoParent
+	}
+	return string(dec.prefix), nil
+}
+
+// Inherit resolves section inheritance in src and writes the result to dst, allocating dst if it
+// is nil. A section inherits from another by setting key (e.g., "extends") to the name of its
+// parent as it would be written in a section heading, such that
+//
+//	[backend a]
+//	host = a.local
+//	port = 80
+//
+//	[backend b]
+//	extends = backend a
+//	host = b.local
+//
+// gives backend.b.host = b.local and backend.b.port = 80. Key is matched as it appears in src, so
+// it should be cased to match the Reader that produced src.
+//
+// A section may set key multiple times to inherit from more than one parent. A derived section
+// receives a copy of each of its parents' keys that it does not define itself. Only a parent's own
+// keys are copied, not those of its subsections, so inheriting from "backend" does not copy
+// backend.a.host. Parents are consulted in the order they're given, so when parents share a key,
+// the first parent's values are used. Parents may themselves inherit from other sections.
+//
+// If a parent section has no keys of its own in src or a section inherits from itself, directly
+// or indirectly, Inherit returns an *InheritanceError. The key naming parents is not copied to dst.
+func (d *Reader) Inherit(dst, src Values, key string) (Values, error) {
+	if dst == nil {
+		dst = make(Values, len(src))
+	}
+
+	in := inheritance{
+		src:      src,
+		key:      key,
+		sep:      d.KeySeparator(),
+		parents:  make(map[string][]string),
+		visiting: make(map[string]bool),
+	}
+
+	// Collect parents of each section
+	for k, vs := range src {
+		prefix, ok := in.parentPrefix(k)
+		if !ok {
+			continue
+		}
+
+		for _, name := range vs {
+			p, err := d.sectionPrefix(name)
+			if err != nil {
+				return nil, &InheritanceError{Section: in.name(prefix), Parent: name, Err: ErrNoParent}
+			}
+			in.parents[prefix] = append(in.parents[prefix], p)
+		}
+	}
+
+	sections := make([]string, 0, len(in.parents))
+	for p := range in.parents {
+		sections = append(sections, p)
+	}
+	sort.Strings(sections)
+
+	in.resolved = make(map[string]map[string][]string, len(sections))
+	for _, p := range sections {
+		if _, err := in.resolve(p); err != nil {
+			return nil, err
+		}
+	}
+
+	for k, vs := range src {
+		if in.isParentKey(k) {
+			continue
+		}
+		dst[k] = append(dst[k], vs...)
+	}
+
+	for _, p := range sections {
+		for name, vs := range in.resolved[p] {
+			k := p + name
+			if _, ok := src[k]; ok {
+				continue
+			}
+			dst[k] = append(dst[k], vs...)
+		}
+	}
+
+	return dst, nil
+}
+
+// inheritance holds the state of resolving section inheritance. Sections are identified by their
+// key prefixes, including the trailing separator.
+type inheritance struct {
+	src      Values
+	key      string
+	sep      string
+	parents  map[string][]string
+	resolved map[string]map[string][]string
+	visiting map[string]bool
+}
+
+// parentPrefix returns the section prefix of k if k is a key naming the parents of a section.
+func (in *inheritance) parentPrefix(k string) (string, bool) {
+	prefix := strings.TrimSuffix(k, in.key)
+	if prefix == k || prefix == "" || !strings.HasSuffix(prefix, in.sep) {
+		return "", false
+	}
+	return prefix, true
+}
+
+func (in *inheritance) isParentKey(k string) bool {
+	prefix, ok := in.parentPrefix(k)
+	if ok {
+		_, ok = in.parents[prefix]
+	}
+	return ok
+}
+
+// name returns the section name of prefix without its trailing separator.
+func (in *inheritance) name(prefix string) string {
+	return strings.TrimSuffix(prefix, in.sep)
+}
+
+// own returns the keys, relative to prefix, that src defines directly under prefix. Keys of the
+// section's subsections are not included.
+func (in *inheritance) own(prefix string) map[string][]string {
+	keys := make(map[string][]string)
+	for k, vs := range in.src {
+		if !strings.HasPrefix(k, prefix) || in.isParentKey(k) {
+			continue
+		} else if name := k[len(prefix):]; in.sep == "" || !strings.Contains(name, in.sep) {
+			keys[name] = vs
+		}
+	}
+	return keys
+}
+
+func (in *inheritance) resolve(prefix string) (map[string][]string, error) {
+	if keys, ok := in.resolved[prefix]; ok {
+		return keys, nil
+	}
+
+	in.visiting[prefix] = true
+	defer delete(in.visiting, prefix)
+
+	keys := in.own(prefix)
+	for _, parent := range in.parents[prefix] {
+		if in.visiting[parent] {
+			return nil, &InheritanceError{
+				Section: in.name(prefix),
+				Parent:  in.name(parent),
+				Err:     ErrInheritanceCycle,
+			}
+		}
+
+		inherited, err := in.resolve(parent)
+		if err != nil {
+			return nil, err
+		} else if len(inherited) == 0 {
+			return nil, &InheritanceError{
+				Section: in.name(prefix),
+				Parent:  in.name(parent),
+				Err:     ErrNoParent,
+			}
+		}
+
+		for name, vs := range inherited {
+			if _, ok := keys[name]; !ok {
+				keys[name] = vs
+			}
+		}
+	}
+
+	in.resolved[prefix] = keys
+	return keys, nil
+}
@@ -0,0 +1,108 @@
+package ini
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInherit(t *testing.T) {
+	src, err := ReadINI([]byte(`
+[backend a]
+host = a.local
+port = 80
+weight = 1
+
+[backend c]
+port = 8080
+tls
+
+[backend b]
+extends = backend a
+extends = `+"`\"backend\" c`"+`
+host = b.local
+
+[backend d]
+extends = backend b
+weight = 2
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DefaultDecoder.Inherit(nil, src, "extends")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Values{
+		"backend.a.host":   []string{"a.local"},
+		"backend.a.port":   []string{"80"},
+		"backend.a.weight": []string{"1"},
+		"backend.c.port":   []string{"8080"},
+		"backend.c.tls":    []string{True},
+		"backend.b.host":   []string{"b.local"},
+		"backend.b.port":   []string{"80"},
+		"backend.b.weight": []string{"1"},
+		"backend.b.tls":    []string{True},
+		"backend.d.host":   []string{"b.local"},
+		"backend.d.port":   []string{"80"},
+		"backend.d.weight": []string{"2"},
+		"backend.d.tls":    []string{True},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Inherit(...) = %#v; want %#v", got, want)
+	}
+}
+
+func TestInherit_subsections(t *testing.T) {
+	src, err := ReadINI([]byte(`
+[backend]
+timeout = 5
+
+[backend a]
+host = a.local
+
+[other]
+extends = backend
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DefaultDecoder.Inherit(nil, src, "extends")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only keys of the parent itself are inherited, not those of its subsections
+	want := Values{
+		"backend.timeout": []string{"5"},
+		"backend.a.host":  []string{"a.local"},
+		"other.timeout":   []string{"5"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Inherit(...) = %#v; want %#v", got, want)
+	}
+}
+
+func TestInherit_errors(t *testing.T) {
+	check := func(src string, want error) {
+		v, err := ReadINI([]byte(src), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = DefaultDecoder.Inherit(nil, v, "extends")
+		var ie *InheritanceError
+		if !errors.As(err, &ie) || !errors.Is(err, want) {
+			t.Errorf("Inherit(%q) = %v; want %v", src, err, want)
+		}
+	}
+
+	check("[a]\nextends = b\n[b]\nextends = a\n", ErrInheritanceCycle)
+	check("[a]\nextends = a\n", ErrInheritanceCycle)
+	check("[a]\nextends = missing\n", ErrNoParent)
+	check("[a]\nextends = `\"unclosed`\n", ErrNoParent)
+	check("[a]\nextends = b] c\n[b]\nk\n", ErrNoParent)
+}
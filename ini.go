@@ -2,9 +2,12 @@
 package ini // import "go.spiff.io/go-ini"
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"runtime"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -35,8 +38,9 @@ const (
 
 	// Values
 
-	rEquals = '='
-	rEscape = '\\'
+	rEquals  = '='
+	rEscape  = '\\'
+	rHeredoc = '<' // Opens a heredoc when doubled, as in <<EOF.
 )
 
 // Values is any set of INI values. This may be used as a Recorder for a Reader.
@@ -109,36 +113,88 @@ func (v Values) WithPrefix(dst Values, prefix string) Values {
 
 // nextfunc is a parsing function that modifies the decoder's state and returns another parsing
 // function. If nextfunc returns io.EOF, parsing is complete. Any other error halts parsing.
-type nextfunc func() (nextfunc, error)
+type nextfunc func(*decoder) (nextfunc, error)
 
-// decoder is a wrapper around an io.Reader for the purpose of doing by-rune parsing of INI file
-// input. It also holds enough state to track line, column, key prefixes (from sections), and
-// errors.
+// decoder is a wrapper around INI input for the purpose of doing by-rune parsing of it. Input is
+// either a byte slice, consumed in place, or an io.Reader read through a bufio.Reader. In both
+// cases, runs of ASCII are scanned directly from the input buffer. The decoder also holds enough
+// state to track line, column, key prefixes (from sections), and errors.
 type decoder struct {
 	true string
 
-	rd       io.Reader
-	readrune func() (rune, int, error)
+	src []byte        // src is unconsumed input when reading from a byte slice
+	rd  *bufio.Reader // rd is the input when reading from an io.Reader (src is unused)
 
 	err    error
 	sep    []byte
 	sep2   [4]byte
 	dst    Recorder
+	dstAt  LocationRecorder // dstAt is dst if it records locations
 	casefn func(rune) rune
 
+	file        string         // file is the name of the input, if known
+	resolver    SecretResolver // resolver resolves references to secrets in values
+	markSecrets bool           // markSecrets adds SecretPrefix to resolved secrets
+	redactor    *Redactor      // redactor determines which values are secret
+	keyStart    mark           // keyStart is the position of the current key
+
+	arrayKeys bool
+	heredoc   bool
+
+	properties bool
+	encoding   Encoding
+	strict     bool // strict is true if invalid UTF-8 is an error
+
+	// DotEnv state
+	dotenv bool
+	getenv func(string) string
+	env    map[string]string // env holds the last value of each key for expansion
+
+	// Recorder state, in case of panics
+	adding                 bool
+	addingKey, addingValue string
+
 	current   rune
+	size      int   // size is the size in bytes of current
+	offset    int64 // offset is the input offset of current
+	pos       int64 // pos is the input offset of the next unread byte
 	line, col int
+	nlCol     int // nlCol is the column of the last newline read
+
+	// lineStart and prevLineStart are the offsets of the current and previous lines.
+	lineStart, prevLineStart int64
 
 	// Storage
 	buffer  bytes.Buffer
 	key     string
-	prefix  []byte   // prefix is prepended to all buffered keys
-	prefix2 [32]byte // prefix2 is a buffer to hold most key prefixes
+	keys    map[string]string // keys interns key strings
+	prefix  []byte            // prefix is prepended to all buffered keys
+	prefix2 [32]byte          // prefix2 is a buffer to hold most key prefixes
 
 	// peek / next state
 	havenext bool
 	next     rune
+	nextSize int
 	nexterr  error
+
+	// Scanner state
+	scan    bool    // scan is true if tokens are being collected
+	tokens  []Token // tokens is the queue of scanned tokens
+	raw     []byte  // raw holds consumed input starting at rawOff
+	rawOff  int64
+	lastEnd int64 // lastEnd is the end offset of the last token
+
+	sectionStart, valueStart mark
+
+	// Limits
+	limits           *Reader
+	truncated        bool            // truncated is true if src was cut short by MaxBytes
+	values           int             // values is the number of values added
+	bufLimit         string          // bufLimit is the name of the limit on the buffer's length
+	bufMax           int             // bufMax is the maximum length of the buffer, if positive
+	segments, segEnd int             // segments and segEnd track the segments of a section name
+	done             <-chan struct{} // done is closed when reading should stop
+	ctx              context.Context
 }
 
 // True is the default value provided to value-less keys in INI files. This is done to treat
@@ -148,32 +204,131 @@ const True string = "1"
 // ReadINI reads an INI file from b, writing the results to the out Values. If out is nil, a new
 // Values is allocated to store the results.
 //
-// ReadINI is a convenience function for calling DefaultDecoder.Read(bytes.NewReader(b), out).
+// ReadINI is a convenience function for calling DefaultDecoder.Read(bytes.NewReader(b), out),
+// except that it reads directly from b.
 func ReadINI(b []byte, out Values) (Values, error) {
 	if out == nil {
 		out = make(Values)
 	}
-	err := DefaultDecoder.Read(bytes.NewReader(b), out)
+	err := DefaultDecoder.readBytes(b, out)
 	if err != nil {
 		return nil, err
 	}
 	return out, err
 }
 
-func (d *decoder) add(key, value string) {
-	if d.dst != nil {
+// add conveys key and value to the decoder's Recorder. If the Recorder panics, the key and value
+// are held by the decoder until the panic is recovered by read.
+func (d *decoder) add(key, value string) error {
+	d.values++
+	d.bufMax = 0
+	if max := d.limits.MaxKeys; max > 0 && d.values > max {
+		return d.limitErr("MaxKeys", int64(max), d.mark())
+	}
+
+	if d.dotenv {
+		if d.env == nil {
+			d.env = make(map[string]string)
+		}
+		d.env[key] = value
+	}
+
+	if d.dst == nil {
+		return nil
+	}
+
+	value, err := d.resolve(key, value)
+	if err != nil {
+		return err
+	}
+
+	d.adding = true
+	d.addingKey, d.addingValue = key, value
+	if d.dstAt != nil {
+		d.dstAt.AddAt(key, value, d.location(d.keyStart))
+	} else {
 		d.dst.Add(key, value)
 	}
+	d.adding = false
+	return nil
+}
+
+// addValue conveys value for the current key to the decoder's Recorder. If the decoder is
+// scanning, it also queues a Value token read from start up to end.
+func (d *decoder) addValue(value string, start mark, end int64) error {
+	if max := d.limits.MaxValueLength; max > 0 && len(value) > max {
+		return d.limitErr("MaxValueLength", int64(max), start)
+	}
+	d.token(Value, value, start, end)
+	return d.add(d.key, value)
+}
+
+// recoverRecorder recovers a panic from the decoder's Recorder, storing it in err as a
+// *RecorderError. Runtime errors and panics from anywhere other than the Recorder are not
+// recovered.
+func (d *decoder) recoverRecorder(err *error) {
+	if !d.adding {
+		return
+	}
+
+	rc := recover()
+	if rc == nil {
+		return
+	} else if _, ok := rc.(runtime.Error); ok {
+		panic(rc)
+	}
+	d.adding = false
+	*err = &RecorderError{Key: d.addingKey, Value: d.redactor.Redact(d.addingKey, d.addingValue), Panic: rc}
 }
 
 func (d *decoder) syntaxerr(err error, msg ...interface{}) *SyntaxError {
 	if se, ok := err.(*SyntaxError); ok {
 		return se
 	}
-	se := &SyntaxError{Line: d.line, Col: d.col, Err: err, Desc: fmt.Sprint(msg...)}
+	se := &SyntaxError{File: d.file, Line: d.line, Col: d.col, Err: err, Desc: fmt.Sprint(msg...)}
 	return se
 }
 
+// readRune reads the next rune of input, without regard to decoder state.
+func (d *decoder) readRune() (r rune, size int, err error) {
+	w := d.window()
+	if len(w) > 0 && w[0] < utf8.RuneSelf {
+		d.consume(w[:1])
+		return rune(w[0]), 1, nil
+	} else if !utf8.FullRune(w) && d.rd != nil {
+		// Fill the buffer enough to hold a rune, if possible
+		w, err = d.rd.Peek(utf8.UTFMax)
+	}
+
+	if len(w) == 0 {
+		if err == nil && d.truncated {
+			err = errMaxBytes
+		} else if err == nil {
+			err = io.EOF
+		}
+		return 0, 0, err
+	}
+
+	r, size = utf8.DecodeRune(w)
+	if r == utf8.RuneError && size == 1 && d.strict {
+		return 0, 0, errInvalidUTF8
+	}
+	d.consume(w[:size])
+	return r, size, nil
+}
+
+// advance updates the line and column for having read r at offset off.
+func (d *decoder) advance(r rune, off int64) {
+	if r == '\n' {
+		d.nlCol = d.col + 1
+		d.line++
+		d.col = 0
+		d.prevLineStart, d.lineStart = d.lineStart, off+1
+	} else {
+		d.col++
+	}
+}
+
 func (d *decoder) nextRune() (r rune, size int, err error) {
 	if d.err != nil {
 		return d.current, utf8.RuneLen(d.current), d.err
@@ -182,24 +337,27 @@ func (d *decoder) nextRune() (r rune, size int, err error) {
 	if d.havenext {
 		r, size, err = d.peekRune()
 		d.havenext = false
-	} else if d.readrune != nil {
-		r, size, err = d.readrune()
 	} else {
-		r, size, err = readrune(d.rd)
+		r, size, err = d.readRune()
 	}
 
-	d.current = r
+	d.current, d.size = r, size
+	d.offset = d.pos - int64(size)
 
+	if err == errMaxBytes {
+		// Report the position of the first rune past the limit
+		m := d.mark()
+		m.col++
+		err = d.limitErr("MaxBytes", d.limits.MaxBytes, m)
+	} else if err == errInvalidUTF8 {
+		err = d.invalidUTF8(d.window()[0])
+	}
 	if err != nil {
 		d.err = err
-		d.rd = nil
-	}
-
-	if d.current == '\n' {
-		d.line++
-		d.col = 1
+		return r, size, err
 	}
 
+	d.advance(r, d.offset)
 	return r, size, err
 }
 
@@ -211,23 +369,105 @@ func (d *decoder) skip() error {
 func (d *decoder) peekRune() (r rune, size int, err error) {
 	if d.havenext {
 		r = d.next
-		size = utf8.RuneLen(r)
+		size = d.nextSize
 		return r, size, d.nexterr
 	}
 
 	// Even if there's an error.
 	d.havenext = true
-	if d.readrune != nil {
-		r, size, err = d.readrune()
+	r, size, err = d.readRune()
+	d.next, d.nextSize, d.nexterr = r, size, err
+	return r, size, err
+}
+
+// window returns the input that is buffered and not yet consumed. The window may be empty even if
+// there is more input to read.
+func (d *decoder) window() []byte {
+	if d.rd == nil {
+		return d.src
+	}
+	b, _ := d.rd.Peek(d.rd.Buffered())
+	return b
+}
+
+// consume discards b, a prefix of the window, from the input. If the decoder is scanning tokens,
+// b is kept as raw input.
+func (d *decoder) consume(b []byte) {
+	if d.scan {
+		d.raw = append(d.raw, b...)
+	}
+
+	d.pos += int64(len(b))
+	if d.rd == nil {
+		d.src = d.src[len(b):]
 	} else {
-		r, size, err = readrune(d.rd)
+		d.rd.Discard(len(b))
+	}
+}
+
+// scanASCII consumes the run of ASCII runes at the start of the buffered input. If it encounters a
+// rune in oneof, it consumes that rune as well and returns true. If buffer is true, runes not in
+// oneof are written to the buffer, mapped by runemap if it is not nil. scanASCII must not be called
+// if there is a peeked rune or error.
+func (d *decoder) scanASCII(oneof *runeset, buffer bool, runemap func(rune) rune) bool {
+	for {
+		window := d.window()
+		i := 0
+		for ; i < len(window); i++ {
+			c := window[i]
+			if c >= utf8.RuneSelf {
+				break
+			} else if oneof.hasByte(c) {
+				d.emit(window[:i], buffer, runemap)
+				d.current, d.size, d.offset = rune(c), 1, d.pos+int64(i)
+				d.advance(d.current, d.offset)
+				d.consume(window[:i+1])
+				return true
+			}
+			d.advance(rune(c), d.pos+int64(i))
+		}
+
+		if i == 0 {
+			return false
+		}
+
+		d.emit(window[:i], buffer, runemap)
+		d.current, d.size, d.offset = rune(window[i-1]), 1, d.pos+int64(i-1)
+		d.consume(window[:i])
+		if i < len(window) {
+			// Stopped on a non-ASCII byte
+			return false
+		} else if d.bufMax > 0 || d.done != nil {
+			// Let readUntil check limits and cancellation between windows
+			return false
+		}
 	}
-	d.next, d.nexterr = r, err
-	return r, size, err
 }
 
-func (d *decoder) readUntil(oneof runeset, buffer bool, runemap func(rune) rune) (err error) {
+// emit writes run, a run of ASCII bytes, to the buffer if buffer is true.
+func (d *decoder) emit(run []byte, buffer bool, runemap func(rune) rune) {
+	if !buffer {
+		return
+	} else if runemap == nil {
+		d.buffer.Write(run)
+		return
+	}
+
+	for _, c := range run {
+		if r := runemap(rune(c)); r >= 0 {
+			d.buffer.WriteRune(r)
+		}
+	}
+}
+
+func (d *decoder) readUntil(oneof *runeset, buffer bool, runemap func(rune) rune) (err error) {
 	for out := &d.buffer; ; {
+		if !d.havenext && d.err == nil && d.scanASCII(oneof, buffer, runemap) {
+			return d.checkBuffer()
+		} else if err = d.checkBuffer(); err != nil {
+			return err
+		}
+
 		var r rune
 		r, _, err = d.nextRune()
 		if err != nil {
@@ -245,6 +485,25 @@ func (d *decoder) readUntil(oneof runeset, buffer bool, runemap func(rune) rune)
 	}
 }
 
+// internKey returns the buffered key b as a string, reusing the string allocated for a prior key
+// with the same bytes if there is one.
+func (d *decoder) internKey(b []byte) string {
+	const maxInterned = 4096
+
+	if s, ok := d.keys[string(b)]; ok {
+		return s
+	}
+
+	s := string(b)
+	if d.keys == nil {
+		d.keys = make(map[string]string)
+	}
+	if len(d.keys) < maxInterned {
+		d.keys[s] = s
+	}
+	return s
+}
+
 func escaped(r rune) rune {
 	switch r {
 	case '0':
@@ -270,31 +529,41 @@ func escaped(r rune) rune {
 
 func (d *decoder) readComment() (next nextfunc, err error) {
 	defer stopOnEOF(&next, &err)
-	next, err = d.readElem, d.readUntil(oneRune(rNewline), true, nil)
+	start := d.mark()
+	next, err = (*decoder).readElem, d.readUntil(setNewline, true, nil)
+	if d.scan && (err == nil || err == io.EOF) {
+		d.token(Comment, strings.TrimSuffix(d.buffer.String(), "\r"), start, d.offset)
+	}
 	return
 }
 
 func isHorizSpace(r rune) bool { return r == ' ' || r == '\t' || r == '\r' }
 
 func (d *decoder) skipSpace(newlines bool) error {
-	fn := unicode.IsSpace
+	fn, until := unicode.IsSpace, setNotSpace
 	if !newlines {
-		fn = isHorizSpace
+		fn, until = isHorizSpace, setNotHorizSpace
 	}
 
 	if fn(d.current) {
-		return d.readUntil(notRune(runeFunc(fn)), false, nil)
+		return d.readUntil(until, false, nil)
 	}
 	return nil
 }
 
-func isKeyEnd(r rune) bool {
-	return r == rEquals || r == rHash || r == rSemicolon || unicode.IsSpace(r)
-}
-
 func casenop(r rune) rune { return r }
 
 func (d *decoder) readKey() (nextfunc, error) {
+	start := d.mark()
+	d.keyStart = start
+	max := d.limits.MaxKeyLength
+	if d.arrayKeys && max > 0 {
+		// Subscripts are rewritten once the key is read, so only the rewritten key is held to max.
+		// Until then, limit the key to the longest that can be rewritten to max bytes: a subscript,
+		// as in [a], is at most three times the length of its rewritten form, and [] is dropped.
+		max = 3*max + 2
+	}
+	d.limitBuffer("MaxKeyLength", max)
 	casefn := d.casefn
 	d.buffer.Write(d.prefix)
 	switch d.current {
@@ -310,43 +579,139 @@ func (d *decoder) readKey() (nextfunc, error) {
 		d.buffer.WriteRune(r)
 	}
 
-	err := d.readUntil(runeFunc(isKeyEnd), true, casefn)
+	err := d.readUntil(setKeyEnd, true, casefn)
+	if le, ok := err.(*LimitError); ok && le.Limit == "MaxKeyLength" {
+		le.Max = int64(d.limits.MaxKeyLength)
+	}
 	if err != nil && err != io.EOF {
 		return nil, err
 	}
 
+	if d.dotenv && err == nil && isHorizSpace(d.current) && d.buffer.String() == "export" {
+		// Skip the export keyword, unless it's the key itself (e.g., "export = 1")
+		err = d.skipSpace(false)
+		if err != nil && err != io.EOF {
+			return nil, err
+		} else if err == nil && !setExportKeyEnd.Contains(d.current) {
+			d.buffer.Reset()
+			return d.readKey()
+		}
+	}
+
+	if d.arrayKeys {
+		if serr := d.readSubscripts(len(d.prefix), err == io.EOF); serr != nil {
+			return nil, serr
+		}
+	}
+
+	if max := d.limits.MaxKeyLength; max > 0 && d.buffer.Len() > max {
+		return nil, d.limitErr("MaxKeyLength", int64(max), start)
+	}
+
+	key := d.internKey(d.buffer.Bytes())
+	d.token(Key, key, start, d.offset)
 	if err == io.EOF {
-		d.add(d.buffer.String(), d.true)
-		return nil, nil
+		return nil, d.add(key, d.true)
 	}
 
-	d.key = d.buffer.String()
+	d.key = key
 	d.buffer.Reset()
+	d.limitBuffer("MaxValueLength", d.limits.MaxValueLength)
 
-	return d.readValueSep, nil
+	return (*decoder).readValueSep, nil
+}
+
+// readSubscripts rewrites the key in the buffer, starting at offset start, from the form
+// name[a][b] to name.a.b (using the decoder's separator). An empty subscript, as in name[], is
+// dropped so that the value is appended to name, and may only occur at the end of the key. If eof
+// is true, the key ended at the end of input.
+func (d *decoder) readSubscripts(start int, eof bool) error {
+	key := d.buffer.Bytes()
+	i := bytes.IndexByte(key[start:], rSectionOpen)
+	if i == -1 {
+		if bytes.IndexByte(key[start:], rSectionClose) != -1 {
+			return d.syntaxerr(BadCharError(rSectionClose), "unexpected closing bracket in key")
+		}
+		return nil
+	} else if i == 0 {
+		return d.syntaxerr(ErrEmptyKey, "keys may not begin with a subscript")
+	}
+	i += start
+
+	out := make([]byte, i, len(key)+len(d.sep))
+	copy(out, key[:i])
+	for rest := key[i:]; len(rest) > 0; {
+		if rest[0] != rSectionOpen {
+			r, _ := utf8.DecodeRune(rest)
+			return d.syntaxerr(BadCharError(r), "expected [ after subscript")
+		}
+
+		j := bytes.IndexByte(rest, rSectionClose)
+		if j == -1 {
+			return d.unclosedSubscript(eof)
+		}
+
+		sub := rest[1:j]
+		rest = rest[j+1:]
+		if bytes.IndexByte(sub, rSectionOpen) != -1 {
+			return d.syntaxerr(UnclosedError(rSectionOpen), "subscripts may not be nested")
+		} else if len(sub) == 0 {
+			if len(rest) > 0 {
+				return d.syntaxerr(ErrEmptyKey, "empty subscripts must end a key")
+			}
+			break
+		}
+
+		out = append(out, d.sep...)
+		out = append(out, sub...)
+	}
+
+	d.buffer.Reset()
+	d.buffer.Write(out)
+	return nil
+}
+
+// unclosedSubscript returns an error for a key whose last subscript is not closed. If the key ended
+// at whitespace followed by more of the key, the subscript contains whitespace; otherwise, the key
+// ended at the end of input or of the key itself (e.g., at = or a newline).
+func (d *decoder) unclosedSubscript(eof bool) error {
+	if !eof && isHorizSpace(d.current) {
+		line, col := d.line, d.col
+		if err := d.skipSpace(false); err == nil && !setKeyEnd.Contains(d.current) {
+			d.line, d.col = line, col
+			return d.syntaxerr(UnclosedError(rSectionOpen), "subscripts may not contain whitespace")
+		}
+	}
+	return d.syntaxerr(UnclosedError(rSectionOpen), "unclosed subscript")
 }
 
 func (d *decoder) readValueSep() (next nextfunc, err error) {
-	if err = must(d.skipSpace(false), io.EOF, nil); err == io.EOF {
-		d.add(d.key, d.true)
-		return nil, nil
+	if err = d.skipSpace(false); err == io.EOF {
+		return nil, d.add(d.key, d.true)
+	} else if err != nil {
+		return nil, err
 	}
 
 	defer stopOnEOF(&next, &err)
 	// Aside from whitespace, the only thing that can follow a key is a newline or =.
 	switch d.current {
 	case rNewline:
-		d.add(d.key, d.true)
-		return d.readElem, d.skip()
+		if err = d.add(d.key, d.true); err != nil {
+			return nil, err
+		}
+		return (*decoder).readElem, d.skip()
 	case rEquals:
 		if err = d.skip(); err == io.EOF {
-			d.add(d.key, "")
-			return nil, nil
+			return nil, d.addValue("", d.mark(), d.offset)
+		} else if d.dotenv {
+			return (*decoder).readEnvValue, err
 		}
-		return d.readValue, nil
+		return (*decoder).readValue, err
 	case rHash, rSemicolon:
-		d.add(d.key, d.true)
-		return d.readComment, nil
+		if err = d.add(d.key, d.true); err != nil {
+			return nil, err
+		}
+		return (*decoder).readComment, nil
 	default:
 		return nil, d.syntaxerr(BadCharError(d.current), "expected either =, newline, or a comment")
 	}
@@ -379,8 +744,40 @@ func (d *decoder) readHexCode(size int) (result rune, err error) {
 	return result, nil
 }
 
+// readEscape reads the escape sequence following a backslash and writes the rune it produces to
+// the buffer. If input ends before the escape sequence does, readEscape returns a syntax error of
+// unclosed and desc.
+func (d *decoder) readEscape(unclosed error, desc string) error {
+	r, _, err := d.nextRune()
+	if err == io.EOF {
+		return d.syntaxerr(unclosed, desc)
+	} else if err != nil {
+		return err
+	}
+
+	switch r {
+	case 'x': // 1 octet
+		r, err = d.readHexCode(2)
+		d.buffer.WriteByte(byte(r & 0xFF))
+	case 'u': // 2 octets
+		r, err = d.readHexCode(4)
+		d.buffer.WriteRune(r)
+	case 'U': // 4 octets
+		r, err = d.readHexCode(8)
+		d.buffer.WriteRune(r)
+	default:
+		d.buffer.WriteRune(escaped(r))
+	}
+	return err
+}
+
 func (d *decoder) readStringValue() (next nextfunc, err error) {
-	err = d.readUntil(runestr(`"\`), true, nil)
+	set := setString
+	if d.dotenv {
+		set = setEnvString
+	}
+
+	err = d.readUntil(set, true, nil)
 	if err == io.EOF {
 		return nil, d.syntaxerr(UnclosedError('"'), "encountered EOF inside string")
 	} else if err != nil {
@@ -391,35 +788,24 @@ func (d *decoder) readStringValue() (next nextfunc, err error) {
 	case '"':
 		if r, _, perr := d.peekRune(); perr == nil && r == rQuote {
 			d.buffer.WriteRune(r)
-			return d.readStringValue, d.skip()
+			return (*decoder).readStringValue, d.skip()
 		}
 	case '\\':
-		r, _, err := d.nextRune()
-		must(err)
-		switch r {
-		case 'x': // 1 octet
-			r, err = d.readHexCode(2)
-			d.buffer.WriteByte(byte(r & 0xFF))
-		case 'u': // 2 octets
-			r, err = d.readHexCode(4)
-			d.buffer.WriteRune(r)
-		case 'U': // 4 octets
-			r, err = d.readHexCode(8)
-			d.buffer.WriteRune(r)
-		default:
-			r = escaped(r)
-			d.buffer.WriteRune(escaped(r))
-		}
-		return d.readStringValue, err
+		err = d.readEscape(UnclosedError('"'), "encountered EOF inside string")
+		return (*decoder).readStringValue, err
+	case '$':
+		return (*decoder).readStringValue, d.readExpansion()
 	}
 
+	if err = d.addValue(d.buffer.String(), d.valueStart, d.offset+int64(d.size)); err != nil {
+		return nil, err
+	}
 	defer stopOnEOF(&next, &err)
-	d.add(d.key, d.buffer.String())
-	return d.readElem, d.skip()
+	return (*decoder).readElem, d.skip()
 }
 
 func (d *decoder) readRawValue() (next nextfunc, err error) {
-	err = d.readUntil(oneRune(rRawQuote), true, nil)
+	err = d.readUntil(setRawString, true, nil)
 	if err == io.EOF {
 		return nil, d.syntaxerr(UnclosedError('`'), "encountered EOF inside raw string")
 	} else if err != nil {
@@ -428,48 +814,158 @@ func (d *decoder) readRawValue() (next nextfunc, err error) {
 
 	if r, _, perr := d.peekRune(); perr == nil && r == rRawQuote {
 		d.buffer.WriteRune(r)
-		return d.readRawValue, d.skip()
+		return (*decoder).readRawValue, d.skip()
 	}
 
+	if err = d.addValue(d.buffer.String(), d.valueStart, d.offset+int64(d.size)); err != nil {
+		return nil, err
+	}
 	defer stopOnEOF(&next, &err)
-	d.add(d.key, d.buffer.String())
-	return d.readElem, d.skip()
+	return (*decoder).readElem, d.skip()
+}
+
+// readHeredoc reads a value of the form <<MARKER, followed by the lines of the value, and ending
+// with a line containing only MARKER. If the marker is prefixed with a hyphen (<<-MARKER), the
+// common leading whitespace of the value's lines is removed. The value does not include the
+// newline preceding the closing marker.
+func (d *decoder) readHeredoc() (next nextfunc, err error) {
+	// Skip the second <, already peeked
+	if err = d.skip(); err != nil {
+		return nil, err
+	}
+
+	err = d.readUntil(setNewline, true, nil)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	marker := bytes.TrimSpace(d.buffer.Bytes())
+	strip := len(marker) > 0 && marker[0] == '-'
+	if strip {
+		marker = marker[1:]
+	}
+
+	if len(marker) == 0 {
+		return nil, d.syntaxerr(ErrBadNewline, "expected heredoc marker")
+	} else if i := bytes.IndexFunc(marker, unicode.IsSpace); i != -1 {
+		r, _ := utf8.DecodeRune(marker[i:])
+		return nil, d.syntaxerr(BadCharError(r), "heredoc markers may not contain whitespace")
+	}
+
+	end := string(marker)
+	var lines []string
+	var n int // n is the length of the value read so far
+	for err != io.EOF {
+		d.buffer.Reset()
+		err = d.readUntil(setNewline, true, nil)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		line := strings.TrimSuffix(d.buffer.String(), "\r")
+		if strings.TrimSpace(line) == end {
+			if strip {
+				stripIndent(lines)
+			}
+			err = d.addValue(strings.Join(lines, "\n"), d.valueStart, d.offset)
+			if err != nil {
+				return nil, err
+			}
+			return (*decoder).readElem, nil
+		}
+		lines = append(lines, line)
+		if n += len(line) + 1; d.bufMax > 0 && n-1 > d.bufMax {
+			return nil, d.limitErr(d.bufLimit, int64(d.bufMax), d.valueStart)
+		}
+	}
+
+	return nil, d.syntaxerr(ErrUnclosedHeredoc, fmt.Sprintf("encountered EOF before heredoc terminator %q", end))
+}
+
+// stripIndent removes the longest run of leading spaces and tabs common to all non-blank lines.
+// Lines containing only whitespace are made empty.
+func stripIndent(lines []string) {
+	var (
+		prefix string
+		found  bool
+	)
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+
+		indent := line[:len(line)-len(trimmed)]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+
+		n := 0
+		for n < len(prefix) && n < len(indent) && prefix[n] == indent[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+
+	for i, line := range lines {
+		if strings.TrimLeft(line, " \t") == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = line[len(prefix):]
+		}
+	}
 }
 
 func (d *decoder) readValue() (next nextfunc, err error) {
-	if err = must(d.skipSpace(false), io.EOF); err == io.EOF {
-		d.add(d.key, "")
-		return nil, nil
+	if err = d.skipSpace(false); err == io.EOF {
+		return nil, d.addValue("", d.mark(), d.offset)
+	} else if err != nil {
+		return nil, err
+	}
+
+	d.valueStart = d.mark()
+	if d.heredoc && d.current == rHeredoc {
+		if r, _, perr := d.peekRune(); perr == nil && r == rHeredoc {
+			return (*decoder).readHeredoc, nil
+		}
 	}
 
 	switch d.current {
 	case rNewline:
 		// Terminated by newline
+		if err = d.addValue("", d.valueStart, d.offset); err != nil {
+			return nil, err
+		}
 		defer stopOnEOF(&next, &err)
-		d.add(d.key, "")
-		return d.readElem, d.skip()
+		return (*decoder).readElem, d.skip()
 	case rQuote:
-		return d.readStringValue, nil
+		return (*decoder).readStringValue, nil
 	case rRawQuote:
-		return d.readRawValue, nil
+		return (*decoder).readRawValue, nil
 	case rHash, rSemicolon:
 		// Terminated by comment
-		d.add(d.key, "")
-		return d.readComment, nil
+		return (*decoder).readComment, d.addValue("", d.valueStart, d.offset)
 	}
 
-	defer stopOnEOF(&next, &err)
 	d.buffer.WriteRune(d.current)
-	must(d.readUntil(runestr("\n;#"), true, nil), io.EOF)
+	if err = d.readUntil(setValueEnd, true, nil); err != nil && err != io.EOF {
+		return nil, err
+	}
 
 	value := string(bytes.TrimRightFunc(d.buffer.Bytes(), unicode.IsSpace))
-	d.add(d.key, value)
-	return d.readElem, err
+	if aerr := d.addValue(value, d.valueStart, d.trimmedEnd(d.valueStart, d.offset)); aerr != nil {
+		return nil, aerr
+	}
+	defer stopOnEOF(&next, &err)
+	return (*decoder).readElem, err
 }
 
 func (d *decoder) readQuotedSubsection() (next nextfunc, err error) {
-	if must(d.readUntil(runestr(`"\`), true, nil), io.EOF) == io.EOF {
+	if err = d.readUntil(setString, true, nil); err == io.EOF {
 		return nil, d.syntaxerr(UnclosedError('"'), "encountered EOF inside quoted section name")
+	} else if err != nil {
+		return nil, err
 	}
 
 	switch d.current {
@@ -477,28 +973,13 @@ func (d *decoder) readQuotedSubsection() (next nextfunc, err error) {
 		var r rune
 		if r, _, err = d.peekRune(); err == nil && r == rQuote {
 			d.buffer.WriteRune(r)
-			return d.readQuotedSubsection, d.skip()
+			return (*decoder).readQuotedSubsection, d.skip()
 		}
 
-		return d.readSubsection, d.skip()
+		return (*decoder).readSubsection, d.skip()
 	case rEscape:
-		r, _, err := d.nextRune()
-		must(err)
-		switch r {
-		case 'x': // 1 octet
-			r, err = d.readHexCode(2)
-			d.buffer.WriteByte(byte(r & 0xFF))
-		case 'u': // 2 octets
-			r, err = d.readHexCode(4)
-			d.buffer.WriteRune(r)
-		case 'U': // 4 octets
-			r, err = d.readHexCode(8)
-			d.buffer.WriteRune(r)
-		default:
-			r = escaped(r)
-			d.buffer.WriteRune(escaped(r))
-		}
-		return d.readQuotedSubsection, nil
+		err = d.readEscape(UnclosedError('"'), "encountered EOF inside quoted section name")
+		return (*decoder).readQuotedSubsection, err
 	}
 	return nil, d.syntaxerr(BadCharError(d.current), "expected a closing quote or escape character")
 }
@@ -508,7 +989,9 @@ func (d *decoder) readHeaderOpen() (nextfunc, error) {
 		// This should be more or less impossible, based on how it's called.
 		return nil, d.syntaxerr(BadCharError(d.current), "expected an opening bracket ('[')")
 	}
-	return d.readSubsection, d.skip()
+	d.sectionStart = d.mark()
+	d.segments, d.segEnd = 0, 0
+	return (*decoder).readSubsection, d.skip()
 }
 
 func (d *decoder) addPrefixSep() {
@@ -520,23 +1003,34 @@ func (d *decoder) addPrefixSep() {
 }
 
 func (d *decoder) readSubsection() (next nextfunc, err error) {
+	if d.buffer.Len() > d.segEnd {
+		d.segments++
+	}
 	d.addPrefixSep()
+	d.segEnd = d.buffer.Len()
 
 	switch d.current {
 	case rSectionClose:
+		if max := d.limits.MaxSectionDepth; max > 0 && d.segments > max {
+			return nil, d.limitErr("MaxSectionDepth", int64(max), d.sectionStart)
+		}
 		if d.buffer.Len() == 0 {
 			d.prefix = d.prefix[:0]
 		} else {
 			d.prefix = append(d.prefix[:0], d.buffer.Bytes()...)
 		}
+		if d.scan {
+			name := string(bytes.TrimSuffix(d.prefix, d.sep))
+			d.token(SectionStart, name, d.sectionStart, d.offset+int64(d.size))
+		}
 		defer stopOnEOF(&next, &err)
-		return d.readElem, d.skip()
+		return (*decoder).readElem, d.skip()
 	case rRawQuote:
 		return nil, d.syntaxerr(ErrSectionRawStr, "raw strings are not allowed in section names")
 	case rQuote:
-		return d.readQuotedSubsection, nil
+		return (*decoder).readQuotedSubsection, nil
 	case rSpace, rTab:
-		return d.readSubsection, d.skipSpace(false)
+		return (*decoder).readSubsection, d.skipSpace(false)
 	case rNewline:
 		return nil, d.syntaxerr(ErrBadNewline, "section headings may not contain unquoted newlines")
 	default:
@@ -553,36 +1047,50 @@ func (d *decoder) readSubsection() (next nextfunc, err error) {
 	}
 	d.buffer.WriteRune(r)
 
-	return d.readSubsection, d.readUntil(runestr(" \t\n\"]"), true, casefn)
+	return (*decoder).readSubsection, d.readUntil(setSubsectionEnd, true, casefn)
 }
 
 func (d *decoder) start() (next nextfunc, err error) {
+	if !d.encoding.transcoded() {
+		d.readBOM()
+	}
 	_, _, err = d.nextRune()
 	if err == io.EOF {
 		return nil, nil
 	}
-	return d.readElem, err
+	return (*decoder).readElem, err
 }
 
 func (d *decoder) readElem() (next nextfunc, err error) {
 	d.buffer.Reset()
+	d.trimRaw()
 
 	if d.err == io.EOF {
 		return nil, nil
 	} else if d.err != nil {
-		return nil, err
+		return nil, d.err
+	}
+
+	if d.properties && !unicode.IsSpace(d.current) {
+		return d.readPropElem()
 	}
 
 	switch d.current {
 	case rSectionOpen:
+		if d.dotenv {
+			return nil, d.syntaxerr(BadCharError(d.current), "dotenv input may not contain sections")
+		}
 		return d.readHeaderOpen()
 	case rHash, rSemicolon:
 		return d.readComment()
 	case ' ', '\t', '\n', '\f', '\r', 0x85, 0xA0:
+		if d.scan {
+			return d.readBlank()
+		}
 		if err = d.skipSpace(true); err == io.EOF {
 			return nil, nil
 		}
-		return d.readElem, err
+		return (*decoder).readElem, err
 	default:
 		return d.readKey()
 	}
@@ -599,19 +1107,44 @@ var defaultSeparator = []byte{'.'}
 // versions of the package.
 const None = "\x00\x00\x13\x15\xff\x00\x12\x00\x13"
 
-func (d *decoder) reset(cfg *Reader, dst Recorder, rd io.Reader) {
+// readBufferSize is the size of the buffer used to read from an io.Reader that isn't already a
+// *bufio.Reader.
+const readBufferSize = 16 << 10
+
+// reset prepares the decoder to read from rd using the configuration cfg. If rd is nil, the
+// decoder reads no input until it is given a byte slice by resetBytes.
+func (d *decoder) reset(ctx context.Context, cfg *Reader, dst Recorder, rd io.Reader) {
 	const defaultBufferCap = 64
 
 	if cfg == nil {
 		cfg = &DefaultDecoder
 	}
 
-	if rx, ok := rd.(runeReader); ok {
-		d.readrune = rx.ReadRune
+	d.limits = cfg
+	d.truncated = false
+	d.values, d.segments, d.segEnd = 0, 0, 0
+	if d.ctx = ctx; ctx != nil {
+		d.done = ctx.Done()
 	} else {
-		d.readrune = nil
+		d.done = nil
 	}
 
+	if rd != nil && (cfg.MaxBytes > 0 || d.done != nil) {
+		rd = &limitReader{r: rd, max: cfg.MaxBytes, done: d.done, ctx: ctx}
+	}
+
+	d.encoding, d.strict = cfg.encoding(), cfg.StrictUTF8
+	if rd != nil && d.encoding.transcoded() {
+		rd = &decodeReader{r: rd, enc: d.encoding}
+	}
+
+	if br, ok := rd.(*bufio.Reader); ok || rd == nil {
+		d.rd = br
+	} else {
+		d.rd = bufio.NewReaderSize(rd, readBufferSize)
+	}
+	d.src = nil
+
 	switch cfg.Casing {
 	case UpperCase:
 		d.casefn = unicode.ToUpper
@@ -621,13 +1154,33 @@ func (d *decoder) reset(cfg *Reader, dst Recorder, rd io.Reader) {
 		d.casefn = nil
 	}
 
-	d.rd = rd
 	d.err = nil
 	d.dst = dst
+	d.dstAt, _ = dst.(LocationRecorder)
+	d.file = ""
+	d.resolver = cfg.Resolver
+	d.markSecrets = cfg.MarkSecrets
+	d.redactor = cfg.Redactor
+	d.adding = false
+	d.arrayKeys = cfg.ArrayKeys
+	d.heredoc = cfg.Heredoc
+	d.properties = cfg.Properties
+	d.dotenv = cfg.DotEnv
+	d.getenv = cfg.Getenv
+	for k := range d.env {
+		delete(d.env, k)
+	}
 
 	d.current = 0
+	d.size, d.offset, d.pos = 0, 0, 0
 	d.line = 1
 	d.col = 0
+	d.lineStart, d.prevLineStart = 0, 0
+
+	d.scan = false
+	d.tokens = d.tokens[:0]
+	d.raw = d.raw[:0]
+	d.rawOff, d.lastEnd = 0, 0
 
 	if cfg.True == None {
 		d.true = ""
@@ -650,6 +1203,9 @@ func (d *decoder) reset(cfg *Reader, dst Recorder, rd io.Reader) {
 	d.buffer.Grow(defaultBufferCap)
 
 	d.key = ""
+	for k := range d.keys {
+		delete(d.keys, k)
+	}
 	if d.prefix == nil {
 		d.prefix = d.prefix2[:0]
 	}
@@ -658,11 +1214,30 @@ func (d *decoder) reset(cfg *Reader, dst Recorder, rd io.Reader) {
 	d.nexterr = nil
 }
 
+// resetBytes prepares the decoder to read from b using the configuration cfg.
+func (d *decoder) resetBytes(ctx context.Context, cfg *Reader, dst Recorder, b []byte) {
+	d.reset(ctx, cfg, dst, nil)
+	if max := cfg.MaxBytes; max > 0 && int64(len(b)) > max {
+		b, d.truncated = b[:max], true
+	}
+	if d.encoding.transcoded() {
+		b = decodeBytes(d.encoding, b)
+	}
+	d.src = b
+}
+
 func (d *decoder) read() (err error) {
-	defer panictoerr(&err)
-	var next nextfunc = d.start
+	defer d.recoverRecorder(&err)
+	var next nextfunc = (*decoder).start
 	for next != nil && err == nil {
-		next, err = next()
+		if err = d.canceled(); err != nil {
+			break
+		}
+		next, err = next(d)
+	}
+
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
 	}
 	return err
 }
@@ -692,11 +1267,21 @@ var DefaultDecoder = Reader{
 
 // Recorder is any type that can accept INI values. Multiple calls to Add may occur with the same
 // key. It is up to the Recorder to decide if it discards or appends to prior versions of a key. If
-// Add panics, the value that it panics with is returned as an error.
+// Add panics with anything other than a runtime error, reading stops and a *RecorderError holding
+// the key, value, and panic value is returned. Runtime errors (e.g., nil dereferences) are not
+// recovered.
 type Recorder interface {
 	Add(key, value string)
 }
 
+// LocationRecorder is a Recorder that also records where in the input each value was set. If a
+// Reader's Recorder is a LocationRecorder, AddAt is called in place of Add with the location of the
+// value's key.
+type LocationRecorder interface {
+	Recorder
+	AddAt(key, value string, loc Location)
+}
+
 // Reader is an INI reader configuration. It does not hold state and may be copied as needed.
 // It is not safe to modify a Reader while Reading, however, as the internal decoder keeps a pointer
 // to the Reader.
@@ -713,44 +1298,110 @@ type Reader struct {
 	// True is the value string used for keys with no value. For example, if True is "T"
 	// (assuming default Separator), given the input "[a b c]\nd", it evaluates to a.b.c.d = T.
 	True string
+	// ArrayKeys enables PHP-style bracketed keys. If true, a key of the form "k[]" appends its
+	// value to the key "k" and a key of the form "k[a][b]" is read as the key "k.a.b" (assuming
+	// the default Separator). Subscripts may not contain whitespace.
+	ArrayKeys bool
+	// Heredoc enables multi-line heredoc values. If true, a value beginning with "<<MARKER" is
+	// read from the following line up to a line containing only MARKER. If the marker is written
+	// as "<<-MARKER", the common leading whitespace of the value's lines is removed.
+	Heredoc bool
+	// Properties reads input as a Java .properties file instead of INI. Unless Encoding is set,
+	// input is decoded as ISO-8859-1. Each line holds either a comment beginning with # or !, or a
+	// key and value separated by =, :, or whitespace. A backslash at the end of a line continues
+	// it on the next line, less leading whitespace, and escapes such as \t and \uXXXX are read in
+	// both keys and values. Values keep trailing whitespace and keys without a value have an empty
+	// value, not True. Section headings are not recognized.
+	Properties bool
+	// DotEnv reads input as a dotenv file instead of INI. Lines are of the form KEY=value and may
+	// begin with "export". Values may be single-quoted, in which case they're read literally, or
+	// double-quoted, in which case escapes are read as in INI strings. Unquoted values end at a
+	// newline or at a # preceded by whitespace. In double-quoted and unquoted values, ${NAME} is
+	// replaced by the value of NAME set earlier in the input or, if there is none, by Getenv. A $
+	// may be escaped in double-quoted values as \$. Section headings are a syntax error.
+	//
+	// Keys are cased according to Casing, so CaseSensitive is usually wanted.
+	DotEnv bool
+	// Getenv, if not nil, is used by DotEnv to expand names not set earlier in the input. It may be
+	// os.Getenv to expand variables from the environment. If Getenv is nil, such names expand to
+	// the empty string.
+	Getenv func(name string) string
+
+	// Encoding is the character encoding of input. If DetectEncoding (the default / zero value),
+	// input is read as UTF-8 unless it begins with a UTF-16 byte order mark.
+	Encoding Encoding
+	// StrictUTF8 makes invalid UTF-8 in input a syntax error. If false, each invalid byte is read
+	// as utf8.RuneError.
+	StrictUTF8 bool
+	// Resolver, if not nil, resolves references to secrets in values, such as "env:DB_PASS" with
+	// an EnvResolver. Only values that are secret are references: values with SecretPrefix, which
+	// is removed before resolving them (e.g., "secret:env:DB_PASS"), values of secret keys (e.g.,
+	// "password = env:DB_PASS"), and encrypted values. Resolved secrets are recorded in place of
+	// their references as plaintext, so Get returns the secret itself, such as the decrypted value
+	// of an encrypted one. Unless MarkSecrets is set, a resolved secret whose key is not a secret
+	// key is therefore not redacted. Values that no resolver handles are recorded as they are. If
+	// a reference cannot be resolved, reading stops with a *SecretError. Scanners do not resolve
+	// secrets.
+	Resolver SecretResolver
+	// MarkSecrets records secrets resolved by Resolver with SecretPrefix, unless their key is a
+	// secret key, so that they remain secret to a Redactor. Get then returns them with the prefix
+	// and Reveal without it, so callers that pass values on must use Reveal.
+	MarkSecrets bool
+	// Redactor determines which keys are secret, both for Resolver and for redacting values from
+	// errors. If nil, the default patterns of a zero Redactor are used.
+	Redactor *Redactor
+
+	// Limits on input. If a limit is exceeded, reading stops with a *LimitError. A limit of zero
+	// or less means there is no limit.
+
+	// MaxBytes is the maximum number of bytes of input to read.
+	MaxBytes int64
+	// MaxKeyLength is the maximum length in bytes of a key, including its section prefix.
+	MaxKeyLength int
+	// MaxValueLength is the maximum length in bytes of a decoded value.
+	MaxValueLength int
+	// MaxKeys is the maximum number of values to record, counting each occurrence of a key.
+	MaxKeys int
+	// MaxSectionDepth is the maximum number of segments in a section heading (e.g., the heading
+	// `[a b "c"]` has three).
+	MaxSectionDepth int
+}
+
+// KeySeparator returns the string the receiver inserts between section names and keys: Separator,
+// or the empty string if Separator is None, or "." (period) if Separator is the empty string.
+func (d *Reader) KeySeparator() string {
+	return separatorOrDefault(d.Separator)
+}
+
+// separatorOrDefault returns the separator sep, accounting for None and the default. If sep is
+// None, it returns the empty string, and if sep is the empty string, it returns "." (period).
+func separatorOrDefault(sep string) string {
+	switch sep {
+	case None:
+		return ""
+	case "":
+		return string(defaultSeparator)
+	default:
+		return sep
+	}
 }
 
 // Read decodes INI file input from r and conveys it to dst. If an error occurs, it is returned. If
 // the error is an EOF before parsing is finished, io.ErrUnexpectedEOF is returned.
 func (d *Reader) Read(r io.Reader, dst Recorder) error {
 	var dec decoder
-	dec.reset(d, dst, r)
+	dec.reset(nil, d, dst, r)
 	return dec.read()
 }
 
-// Utility functions
-
-func panictoerr(err *error) {
-	rc := recover()
-	if perr, ok := rc.(error); ok {
-		*err = perr
-	} else if rc != nil {
-		*err = fmt.Errorf("ini: panic: %v", rc)
-	}
-
-	if *err == io.EOF {
-		*err = io.ErrUnexpectedEOF
-	}
+// readBytes decodes INI input from b and conveys it to dst.
+func (d *Reader) readBytes(b []byte, dst Recorder) error {
+	var dec decoder
+	dec.resetBytes(nil, d, dst, b)
+	return dec.read()
 }
 
-func must(err error, allowed ...error) error {
-	if err == nil {
-		return err
-	}
-
-	for _, e := range allowed {
-		if e == err {
-			return err
-		}
-	}
-
-	panic(err)
-}
+// Utility functions
 
 func stopOnEOF(next *nextfunc, err *error) {
 	if *err == io.EOF {
@@ -761,44 +1412,63 @@ func stopOnEOF(next *nextfunc, err *error) {
 
 // Rune handling
 
-type runeReader interface {
-	ReadRune() (rune, int, error)
-}
-
-func readrune(rd io.Reader) (r rune, size int, err error) {
-	if rd, ok := rd.(runeReader); ok {
-		return rd.ReadRune()
-	}
-	var b [4]byte
-	for i, t := 0, 1; i < len(b); i, t = i+1, t+1 {
-		_, err = rd.Read(b[i:t])
-		if err != nil {
-			return r, size, err
-		} else if c := b[:t]; utf8.FullRune(c) {
-			r, size = utf8.DecodeRune(c)
-			return r, size, err
-		}
-	}
+// asciiSpace is the set of ASCII runes for which unicode.IsSpace is true.
+const asciiSpace = "\t\n\v\f\r "
 
-	return unicode.ReplacementChar, 1, nil
+// runeset is a set of runes. Membership of ASCII runes is held in a bitmap so that scanning ASCII
+// input does not require a function call per rune. If fn is not nil, it decides membership of
+// all non-ASCII runes; otherwise, no non-ASCII runes are in the set.
+type runeset struct {
+	ascii [2]uint64
+	fn    func(rune) bool
 }
 
-type (
-	runeset interface {
-		Contains(rune) bool
+// newRuneset returns a runeset of the ASCII runes in ascii and the non-ASCII runes for which fn
+// returns true.
+func newRuneset(ascii string, fn func(rune) bool) *runeset {
+	s := &runeset{fn: fn}
+	for i := 0; i < len(ascii); i++ {
+		c := ascii[i]
+		s.ascii[c>>6] |= 1 << (c & 63)
 	}
+	return s
+}
 
-	oneRune  rune
-	runeFunc func(rune) bool
-	runestr  string
-)
-
-func notRune(runes runeset) runeset {
-	return runeFunc(func(r rune) bool { return !runes.Contains(r) })
+// newRunesetExcept returns a runeset of all ASCII runes other than those in ascii and the
+// non-ASCII runes for which fn returns true.
+func newRunesetExcept(ascii string, fn func(rune) bool) *runeset {
+	s := newRuneset(ascii, fn)
+	s.ascii[0], s.ascii[1] = ^s.ascii[0], ^s.ascii[1]
+	return s
 }
 
-func (s runestr) Contains(r rune) bool { return strings.ContainsRune(string(s), r) }
+func (s *runeset) hasByte(c byte) bool { return s.ascii[c>>6]&(1<<(c&63)) != 0 }
 
-func (fn runeFunc) Contains(r rune) bool { return fn(r) }
+func (s *runeset) Contains(r rune) bool {
+	if r >= 0 && r < utf8.RuneSelf {
+		return s.hasByte(byte(r))
+	}
+	return s.fn != nil && s.fn(r)
+}
 
-func (lhs oneRune) Contains(rhs rune) bool { return rune(lhs) == rhs }
+func isNotSpace(r rune) bool { return !unicode.IsSpace(r) }
+
+func isAny(rune) bool { return true }
+
+var (
+	setNewline       = newRuneset("\n", nil)
+	setKeyEnd        = newRuneset("=#;"+asciiSpace, unicode.IsSpace)
+	setString        = newRuneset(`"\`, nil)
+	setEnvString     = newRuneset(`"\$`, nil)
+	setEnvValueEnd   = newRuneset("\n#$", nil)
+	setLiteral       = newRuneset("'", nil)
+	setExpansionEnd  = newRuneset("}\n", nil)
+	setExportKeyEnd  = newRuneset("=#\n", nil)
+	setPropKeyEnd    = newRuneset("=: \t\f\r\n\\", nil)
+	setPropValueEnd  = newRuneset("\n\\", nil)
+	setRawString     = newRuneset("`", nil)
+	setValueEnd      = newRuneset("\n;#", nil)
+	setSubsectionEnd = newRuneset(" \t\n\"]", nil)
+	setNotSpace      = newRunesetExcept(asciiSpace, isNotSpace)
+	setNotHorizSpace = newRunesetExcept(" \t\r", isAny)
+)
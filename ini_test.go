@@ -2,8 +2,11 @@ package ini
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 	"testing/iotest"
@@ -26,18 +29,47 @@ var failReaders = map[string]func(string) io.Reader{
 	"iotest.TimeoutReader": func(s string) io.Reader { return iotest.TimeoutReader(strings.NewReader(s)) },
 }
 
-func TestPanicToErr_nonerr(t *testing.T) {
-	var err error
-	func() {
-		defer panictoerr(&err)
-		panic("foobar!")
-	}()
+// panicRecorder is a Recorder that panics with its value when Add is called.
+type panicRecorder struct{ v interface{} }
+
+func (p panicRecorder) Add(string, string) { panic(p.v) }
+
+func TestRecorderError(t *testing.T) {
+	errPanic := errors.New("panic error")
+	for _, v := range []interface{}{"foobar!", errPanic} {
+		err := DefaultDecoder.Read(strings.NewReader("[a] b = c"), panicRecorder{v})
+		re, ok := err.(*RecorderError)
+		if !ok {
+			t.Errorf("err(%T) = %v; want *RecorderError", err, err)
+			continue
+		}
+
+		if re.Key != "a.b" || re.Value != "c" || re.Panic != v {
+			t.Errorf("err = %#v; want key=a.b value=c panic=%v", re, v)
+		}
+		if want := fmt.Sprintf(`ini: recorder panicked adding "a.b": %v`, v); re.Error() != want {
+			t.Errorf("err.Error() = %q; want %q", re.Error(), want)
+		}
+	}
 
-	if want := "ini: panic: foobar!"; err == nil || err.Error() != want {
-		t.Errorf("err(%T) = %v; want %q", err, err, want)
+	err := DefaultDecoder.Read(strings.NewReader("k"), panicRecorder{errPanic})
+	if !errors.Is(err, errPanic) {
+		t.Errorf("err = %v; want to wrap %v", err, errPanic)
 	}
 }
 
+func TestRecorderError_runtime(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(runtime.Error); !ok {
+			t.Error("expected a runtime error panic")
+		}
+	}()
+
+	var nilValues Values
+	_ = DefaultDecoder.Read(strings.NewReader("k = v"), nilValues)
+	t.Error("expected Read to panic")
+}
+
 func TestReadINI_altsep(t *testing.T) {
 	dec := Reader{
 		Separator: "_-_",
@@ -404,3 +436,232 @@ func testReadINIError(t *testing.T, b string) error {
 
 	return err
 }
+
+// testReadINIErrorWith reads b with dec from both an io.Reader and a byte slice, expecting an error
+// from each, and returns the error from the io.Reader.
+func testReadINIErrorWith(t *testing.T, dec *Reader, b string) error {
+	defer pushlog(t)()
+	err := dec.Read(strings.NewReader(b), Values{})
+	if err == nil {
+		elog(1, "Expected error, got nil")
+	} else {
+		dlog(1, "Error returned: ", err)
+	}
+
+	if berr := dec.readBytes([]byte(b), Values{}); berr == nil {
+		elog(1, "Expected error from byte slice, got nil")
+	} else if err != nil && berr.Error() != err.Error() {
+		elogf(1, "Error from byte slice = %v; want %v", berr, err)
+	}
+
+	return err
+}
+
+func TestReadINI_arrayKeys(t *testing.T) {
+	dec := Reader{ArrayKeys: true}
+	testReadINIMatching(t, &dec, `
+		list[] = a
+		list[] = b
+		[section]
+		map[x] = 1
+		map[y][z] = 2
+		map[w][]
+		plain = 3
+		`,
+		Values{
+			"list":            []string{"a", "b"},
+			"section.map.x":   []string{"1"},
+			"section.map.y.z": []string{"2"},
+			"section.map.w":   []string{True},
+			"section.plain":   []string{"3"},
+		})
+
+	dec.Separator = ":"
+	testReadINIMatching(t, &dec, "[a] k[b]", Values{"a:k:b": []string{True}})
+
+	// Without ArrayKeys, brackets are part of the key.
+	testReadINIMatching(t, nil, "k[] = v", Values{"k[]": []string{"v"}})
+
+	for _, src := range []string{
+		"k[ = v",
+		"k[a = v",
+		"k[a b] = v",
+		"k] = v",
+		"k[a]b = v",
+		"k[][a] = v",
+		"k[a[b]] = v",
+		"k[a]] = v",
+	} {
+		err := testReadINIErrorWith(t, &dec, src)
+		if se, ok := err.(*SyntaxError); !ok {
+			t.Errorf("Read(%q) error = %#v; want *SyntaxError", src, err)
+		} else if _, ok := se.Err.(UnclosedError); strings.Count(src, "[") > strings.Count(src, "]") && !ok {
+			t.Errorf("Read(%q) error = %v; want UnclosedError", src, err)
+		}
+	}
+
+	for src, want := range map[string]string{
+		"k[a = v":    "unclosed subscript",
+		"k[a":        "unclosed subscript",
+		"k[a\nx = 1": "unclosed subscript",
+		"k[a b] = v": "subscripts may not contain whitespace",
+	} {
+		err := testReadINIErrorWith(t, &dec, src)
+		if se, ok := err.(*SyntaxError); !ok || se.Desc != want {
+			t.Errorf("Read(%q) error = %v; want %q", src, err, want)
+		}
+	}
+}
+
+func TestReadINI_heredoc(t *testing.T) {
+	dec := Reader{Heredoc: true}
+	testReadINIMatching(t, &dec, "[tls]\ncert = <<PEM\n-----BEGIN-----\nQUJD `\"\n-----END-----\nPEM\nnext = 1\n",
+		Values{
+			"tls.cert": []string{"-----BEGIN-----\nQUJD `\"\n-----END-----"},
+			"tls.next": []string{"1"},
+		})
+
+	testReadINIMatching(t, &dec, `
+		[db]
+		query = <<-SQL
+			SELECT *
+			  FROM t
+
+			 WHERE x = 1; -- not a comment
+			SQL
+		other = <<EOF
+EOF`,
+		Values{
+			"db.query": []string{"SELECT *\n  FROM t\n\n WHERE x = 1; -- not a comment"},
+			"db.other": []string{""},
+		})
+
+	testReadINIMatching(t, &dec, "k = <<EOF\r\na\r\nEOF\r\n", Values{"k": []string{"a"}})
+
+	// A single < is an ordinary value, as is << without Heredoc.
+	testReadINIMatching(t, &dec, "k = <a>", Values{"k": []string{"<a>"}})
+	testReadINIMatching(t, nil, "k = <<EOF", Values{"k": []string{"<<EOF"}})
+
+	for _, src := range []string{
+		"k = <<EOF\nnever closed\n",
+		"k = <<EOF",
+		"k = <<\nEOF\n",
+		"k = <<-\nEOF\n",
+		"k = <<E F\nE F\n",
+	} {
+		testReadINIErrorWith(t, &dec, src)
+	}
+
+	err := testReadINIErrorWith(t, &dec, "\nk = <<EOF\na\nb\n")
+	if se, ok := err.(*SyntaxError); !ok || se.Line != 5 {
+		t.Errorf("err = %v; want *SyntaxError on line 5", err)
+	} else if se.Err != ErrUnclosedHeredoc {
+		t.Errorf("err = %v; want %v", err, ErrUnclosedHeredoc)
+	}
+	const want = `ini: syntax error at 5:0: ini: unclosed heredoc -- encountered EOF before heredoc terminator "EOF"`
+	if got := err.Error(); got != want {
+		t.Errorf("err = %q; want %q", got, want)
+	}
+}
+
+// benchmarkInput returns at least size bytes of INI input with a mix of sections, comments, quoted
+// and unquoted values, and repeated keys.
+func benchmarkInput(size int) []byte {
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < size; i++ {
+		fmt.Fprintf(&buf, "; Section %d\n[backend \"host-%d\"]\n", i, i)
+		buf.WriteString("address = 10.0.0.1:8080\n")
+		buf.WriteString("timeout = 30s ; seconds\n")
+		buf.WriteString("name = \"a quoted \\\"value\\\" with escapes\\n\"\n")
+		buf.WriteString("path = `/raw/path/with ``ticks```\n")
+		buf.WriteString("enabled\n\n")
+	}
+	return buf.Bytes()
+}
+
+// plainReader hides any methods other than Read from the decoder.
+type plainReader struct{ io.Reader }
+
+func BenchmarkReadINI(b *testing.B) {
+	src := benchmarkInput(4 << 20)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadINI(src, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReader_Read(b *testing.B) {
+	src := benchmarkInput(4 << 20)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := DefaultDecoder.Read(plainReader{bytes.NewReader(src)}, Values{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// discard is a Recorder that discards all values.
+type discard struct{}
+
+func (discard) Add(string, string) {}
+
+func BenchmarkReader_Discard(b *testing.B) {
+	src := benchmarkInput(4 << 20)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := DefaultDecoder.Read(plainReader{bytes.NewReader(src)}, discard{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadINI_small(b *testing.B) {
+	src := benchmarkInput(1)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadINI(src, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	var got []Entry
+	for e, err := range Parse(strings.NewReader("a = 1\n[s]\nb\nc = \"x\"\n")) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+
+	want := []Entry{{"a", "1"}, {"s.b", True}, {"s.c", "x"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %v; want %v", got, want)
+	}
+
+	var (
+		n    int
+		last error
+	)
+	for _, err := range Parse(strings.NewReader("a = 1\nb = \"open")) {
+		n++
+		last = err
+	}
+	if _, ok := last.(*SyntaxError); n != 2 || !ok {
+		t.Errorf("Parse() yielded %d entries ending in %v; want 2 ending in *SyntaxError", n, last)
+	}
+
+	for range Parse(strings.NewReader("a\nb\nc")) {
+		break
+	}
+}
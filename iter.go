@@ -0,0 +1,126 @@
+package ini
+
+import (
+	"io"
+	"iter"
+	"sort"
+	"strings"
+)
+
+// sortedKeys returns the keys of v in sorted order.
+func (v Values) sortedKeys() []string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// All returns an iterator over every key and value in the receiver. Keys are visited in sorted
+// order and a key's values are visited in the order they were added. Keys with no values are not
+// visited.
+func (v Values) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, k := range v.sortedKeys() {
+			for _, value := range v[k] {
+				if !yield(k, value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys of the receiver in sorted order.
+func (v Values) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, k := range v.sortedKeys() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Sections returns an iterator over the names of sections with keys in the receiver, in sorted
+// order. A key's section is everything before its last sep, so with the separator "." the key
+// "a.b.c" is in the section "a.b". Keys without a separator are not in a section. If sep is the
+// empty string, it defaults to "." (period), and if sep is None, no key is in a section.
+func (v Values) Sections(sep string) iter.Seq[string] {
+	sep = separatorOrDefault(sep)
+	return func(yield func(string) bool) {
+		if sep == "" {
+			return
+		}
+
+		seen := make(map[string]struct{})
+		for k := range v {
+			if i := strings.LastIndex(k, sep); i != -1 {
+				seen[k[:i]] = struct{}{}
+			}
+		}
+
+		sections := make([]string, 0, len(seen))
+		for s := range seen {
+			sections = append(sections, s)
+		}
+		sort.Strings(sections)
+
+		for _, s := range sections {
+			if !yield(s) {
+				return
+			}
+		}
+	}
+}
+
+// Entry is a single key and value read from INI input.
+type Entry struct {
+	Key, Value string
+}
+
+// entryQueue is a Recorder that queues entries.
+type entryQueue []Entry
+
+func (q *entryQueue) Add(key, value string) {
+	*q = append(*q, Entry{Key: key, Value: value})
+}
+
+// Parse returns an iterator over the keys and values read from r by DefaultDecoder, as they are
+// parsed.
+func Parse(r io.Reader) iter.Seq2[Entry, error] {
+	return DefaultDecoder.Parse(r)
+}
+
+// Parse returns an iterator over the keys and values read from r, in the order they occur. Entries
+// are parsed as the iterator advances, but r is read through a buffer, so it may be read past the
+// last entry visited. If an error occurs, it is yielded with an empty Entry and iteration stops. As
+// with Read, the receiver must not be modified while iterating.
+func (d *Reader) Parse(r io.Reader) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		var (
+			dec   decoder
+			queue entryQueue
+			err   error
+		)
+		dec.reset(nil, d, &queue, r)
+
+		for next := nextfunc((*decoder).start); next != nil && err == nil; {
+			next, err = next(&dec)
+			for _, e := range queue {
+				if !yield(e, nil) {
+					return
+				}
+			}
+			queue = queue[:0]
+		}
+
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			yield(Entry{}, err)
+		}
+	}
+}
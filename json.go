@@ -0,0 +1,169 @@
+package ini
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// JSONOptions controls how ToJSON and FromJSON convert between Values and JSON.
+type JSONOptions struct {
+	// Separator is the string between a section name and a key. Keys are split on Separator into
+	// nested JSON objects. If Separator is None, keys are not split. If Separator is the empty
+	// string, it defaults to "." (period).
+	Separator string
+	// InferTypes writes values that look like JSON numbers or booleans as numbers or booleans
+	// instead of strings.
+	InferTypes bool
+	// Indent, if not empty, is used to indent the JSON written by ToJSON, one level per nested
+	// object or array.
+	Indent string
+}
+
+// splitKey returns key split on the separator sep, which must already account for None and the
+// default, as returned by separatorOrDefault.
+func splitKey(key, sep string) []string {
+	if sep != "" {
+		return strings.Split(key, sep)
+	}
+	return []string{key}
+}
+
+// ToJSON returns v as a JSON object. Sections become nested objects, keys with a single value
+// become strings, and keys with any other number of values become arrays of strings. If a key is
+// also the name of a section, as with "a" and "a.b", its values are stored under the empty key of
+// the section's object: {"a": {"": "1", "b": "2"}}. As with ToTree, ToJSON returns an error if two
+// keys have the same place in the object.
+func ToJSON(v Values, opts JSONOptions) ([]byte, error) {
+	tree, err := ToTree(v, opts.Separator)
+	if err != nil {
+		return nil, err
+	}
+	if opts.InferTypes {
+		inferTypes(tree)
+	}
+
+	if opts.Indent == "" {
+		return json.Marshal(tree)
+	}
+	return json.MarshalIndent(tree, "", opts.Indent)
+}
+
+// inferTypes replaces the strings of tree that look like JSON numbers or booleans with numbers or
+// booleans.
+func inferTypes(tree map[string]interface{}) {
+	for k, value := range tree {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			inferTypes(value)
+		case string:
+			tree[k] = jsonScalar(value)
+		case []string:
+			elems := make([]interface{}, len(value))
+			for i, s := range value {
+				elems[i] = jsonScalar(s)
+			}
+			tree[k] = elems
+		}
+	}
+}
+
+// jsonScalar returns value as a JSON number or boolean if it looks like one, and otherwise as a
+// string.
+func jsonScalar(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if jsonNumber(value) {
+		return json.Number(value)
+	}
+	return value
+}
+
+// jsonNumber returns whether s is exactly a JSON number: an optional minus sign, an integer with no
+// leading zeros, an optional fraction, and an optional exponent.
+func jsonNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	switch {
+	case s == "" || !isDecDigit(s[0]):
+		return false
+	case s[0] == '0':
+		s = s[1:]
+	default:
+		s = skipDecDigits(s)
+	}
+
+	if strings.HasPrefix(s, ".") {
+		if frac := s[1:]; frac == "" || !isDecDigit(frac[0]) {
+			return false
+		}
+		s = skipDecDigits(s[1:])
+	}
+	if s != "" && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if s != "" && (s[0] == '+' || s[0] == '-') {
+			s = s[1:]
+		}
+		if s == "" || !isDecDigit(s[0]) {
+			return false
+		}
+		s = skipDecDigits(s)
+	}
+	return s == ""
+}
+
+// skipDecDigits returns s without its leading decimal digits.
+func skipDecDigits(s string) string {
+	i := 0
+	for i < len(s) && isDecDigit(s[i]) {
+		i++
+	}
+	return s[i:]
+}
+
+// FromJSON converts a JSON object to Values, as FromTree does. Numbers are kept as written in the
+// JSON input. It is an error for anything other than whitespace to follow the object.
+func FromJSON(data []byte, opts JSONOptions) (Values, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var tree map[string]interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("ini: unexpected data after JSON object at offset %d", dec.InputOffset())
+	}
+	return FromTree(tree, opts.Separator)
+}
+
+// NewDocument returns a Document containing the keys and values of v. Keys are grouped by section,
+// as determined by the receiver's separator, and written in sorted order.
+func (d *Reader) NewDocument(v Values) (*Document, error) {
+	doc, err := d.ParseDocument(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write keys without a section first, so they are not mistaken for keys of a section
+	keys := v.sortedKeys()
+	sep := d.KeySeparator()
+	sort.SliceStable(keys, func(i, j int) bool {
+		return sep != "" && !strings.Contains(keys[i], sep) && strings.Contains(keys[j], sep)
+	})
+
+	for _, k := range keys {
+		for _, value := range v[k] {
+			if err := doc.Add(k, value); err != nil {
+				return nil, fmt.Errorf("ini: adding %q: %w", k, err)
+			}
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,122 @@
+package ini
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	v := Values{
+		"top":     {"x"},
+		"a":       {"leaf"},
+		"a.b":     {"1", "two"},
+		"a.c.d":   {"true"},
+		"a.c.e":   {"-1.5e3"},
+		"a.c.f":   {"01"},
+		"empty":   nil,
+		"s.t.u.v": {""},
+	}
+
+	cases := []struct {
+		opts JSONOptions
+		want string
+	}{
+		{JSONOptions{}, `{"a":{"":"leaf","b":["1","two"],"c":{"d":"true","e":"-1.5e3","f":"01"}},` +
+			`"empty":[],"s":{"t":{"u":{"v":""}}},"top":"x"}`},
+		{JSONOptions{InferTypes: true}, `{"a":{"":"leaf","b":[1,"two"],"c":{"d":true,"e":-1.5e3,"f":"01"}},` +
+			`"empty":[],"s":{"t":{"u":{"v":""}}},"top":"x"}`},
+		{JSONOptions{Separator: None}, `{"a":"leaf","a.b":["1","two"],"a.c.d":"true","a.c.e":"-1.5e3",` +
+			`"a.c.f":"01","empty":[],"s.t.u.v":"","top":"x"}`},
+	}
+	for _, c := range cases {
+		got, err := ToJSON(v, c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != c.want {
+			t.Errorf("ToJSON(%+v) =\n%s\nwant\n%s", c.opts, got, c.want)
+		}
+
+		back, err := FromJSON(got, c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(back, v) {
+			t.Errorf("FromJSON(ToJSON(%+v)) = %v; want %v", c.opts, back, v)
+		}
+	}
+
+	got, err := ToJSON(Values{"a/b": {"1"}}, JSONOptions{Separator: "/", Indent: " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n \"a\": {\n  \"b\": \"1\"\n }\n}"; string(got) != want {
+		t.Errorf("ToJSON(/) = %q; want %q", got, want)
+	}
+}
+
+func TestJSONScalar(t *testing.T) {
+	cases := map[string]interface{}{
+		"0":       json.Number("0"),
+		"-12":     json.Number("-12"),
+		"1.25":    json.Number("1.25"),
+		"2E+10":   json.Number("2E+10"),
+		"-0.5e-3": json.Number("-0.5e-3"),
+		"true":    true,
+		"1 ":      "1 ",
+		" 1":      " 1",
+		"1\n":     "1\n",
+		"01":      "01",
+		"-":       "-",
+		"1.":      "1.",
+		".5":      ".5",
+		"1e":      "1e",
+		"+1":      "+1",
+		"0x10":    "0x10",
+	}
+	for in, want := range cases {
+		if got := jsonScalar(in); got != want {
+			t.Errorf("jsonScalar(%q) = %#v; want %#v", in, got, want)
+		}
+	}
+
+	got, err := ToJSON(Values{"a": {"1 "}}, JSONOptions{InferTypes: true})
+	if err != nil {
+		t.Fatal(err)
+	} else if want := `{"a":"1 "}`; string(got) != want {
+		t.Errorf("ToJSON(1 ) = %s; want %s", got, want)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	got, err := FromJSON([]byte(`{"a": {"b": 1, "c": [true, "x"], "": "self"}, "n": null, "big": 12345678901234567890}`), JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Values{"a": {"self"}, "a.b": {"1"}, "a.c": {"true", "x"}, "n": nil, "big": {"12345678901234567890"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromJSON() = %v; want %v", got, want)
+	}
+
+	for _, in := range []string{`[]`, `{"a": [{"b": 1}]}`, `{"a": [[1]]}`, `{`, `{"a": 1} garbage`, `{"a": 1}{"b": 2}`, `{} ]`} {
+		if _, err := FromJSON([]byte(in), JSONOptions{}); err == nil {
+			t.Errorf("FromJSON(%s) = nil; want error", in)
+		}
+	}
+}
+
+func TestReader_NewDocument(t *testing.T) {
+	v := Values{"s.b": {"1", "2"}, "top": {"x y"}, "s.t.c": {"3"}, "a": {";"}}
+	doc, err := DefaultDecoder.NewDocument(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a = \";\"\ntop = x y\n\n[s]\nb = 1\nb = 2\nt.c = 3\n"
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("NewDocument() =\n%s\nwant\n%s", got, want)
+	}
+	if got := doc.Values(); !reflect.DeepEqual(got, v) {
+		t.Errorf("Values() = %v; want %v", got, v)
+	}
+}
@@ -0,0 +1,107 @@
+package ini
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// errMaxBytes is returned by a limitReader when its limit is exceeded. The decoder replaces it with
+// a *LimitError.
+var errMaxBytes = errors.New("ini: MaxBytes exceeded")
+
+// ReadContext decodes INI input from r as DefaultDecoder and conveys it to dst, stopping if ctx is
+// done.
+func ReadContext(ctx context.Context, r io.Reader, dst Recorder) error {
+	return DefaultDecoder.ReadContext(ctx, r, dst)
+}
+
+// ReadContext decodes INI input from r and conveys it to dst, as Read does. If ctx is done before
+// reading finishes, ReadContext stops and returns ctx.Err(). Cancellation is checked between
+// elements of input, between reads from r, and while reading long keys and values, but a read from
+// r that blocks is not interrupted.
+func (d *Reader) ReadContext(ctx context.Context, r io.Reader, dst Recorder) error {
+	var dec decoder
+	dec.reset(ctx, d, dst, r)
+	return dec.read()
+}
+
+// canceled returns the decoder's context error if its context is done.
+func (d *decoder) canceled() error {
+	if d.done == nil {
+		return nil
+	}
+
+	select {
+	case <-d.done:
+		return d.ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// limitBuffer limits the length of the buffer to max bytes, the value of the named limit, until the
+// next value is added. If max is zero or less, the buffer is not limited.
+func (d *decoder) limitBuffer(limit string, max int) {
+	d.bufLimit, d.bufMax = limit, max
+}
+
+// checkBuffer returns a *LimitError if the buffer is longer than its limit and the decoder's
+// context error if its context is done. It is checked as input is buffered, so that neither an
+// overlong key or value nor a canceled context has to wait for the end of the key or value.
+func (d *decoder) checkBuffer() error {
+	if d.bufMax > 0 && d.buffer.Len() > d.bufMax {
+		m := d.keyStart
+		if d.bufLimit == "MaxValueLength" {
+			m = d.valueStart
+		}
+		return d.limitErr(d.bufLimit, int64(d.bufMax), m)
+	}
+	return d.canceled()
+}
+
+// limitErr returns a *LimitError for the named limit at the position m.
+func (d *decoder) limitErr(limit string, max int64, m mark) *LimitError {
+	return &LimitError{Limit: limit, Max: max, Line: m.line, Col: m.col}
+}
+
+// limitReader is an io.Reader that stops reading once a context is done or, if max is positive,
+// once more than max bytes are read from r.
+type limitReader struct {
+	r    io.Reader
+	max  int64 // max is the number of bytes that may be read or zero if unlimited
+	n    int64 // n is the number of bytes read
+	done <-chan struct{}
+	ctx  context.Context
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.done != nil {
+		select {
+		case <-l.done:
+			return 0, l.ctx.Err()
+		default:
+		}
+	}
+
+	if l.max <= 0 {
+		return l.r.Read(p)
+	}
+
+	if l.n >= l.max {
+		// Read a single byte to check whether there is input past the limit
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			return 0, errMaxBytes
+		}
+		return 0, err
+	}
+
+	if rem := l.max - l.n; int64(len(p)) > rem {
+		p = p[:rem]
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,164 @@
+package ini
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReader_limits(t *testing.T) {
+	cases := []struct {
+		name string
+		dec  Reader
+		src  string
+		want LimitError
+	}{
+		{"MaxBytes", Reader{MaxBytes: 8}, "a = 1\nb = 2\n", LimitError{"MaxBytes", 8, 2, 3}},
+		{"MaxKeyLength", Reader{MaxKeyLength: 3}, "abc = 1\n[s]\nabc = 2", LimitError{"MaxKeyLength", 3, 3, 1}},
+		{"MaxValueLength", Reader{MaxValueLength: 4}, "a = 1234\nb = \"12345\"", LimitError{"MaxValueLength", 4, 2, 5}},
+		{"MaxKeys", Reader{MaxKeys: 2}, "a = 1\na = 2\nflag", LimitError{"MaxKeys", 2, 3, 4}},
+		{"MaxSectionDepth", Reader{MaxSectionDepth: 2}, "[a b]\n[a b \"c\"]", LimitError{"MaxSectionDepth", 2, 2, 1}},
+		{"MaxKeyLength ArrayKeys", Reader{MaxKeyLength: 3, ArrayKeys: true}, "k[a] = 1\nk[ab] = 2", LimitError{"MaxKeyLength", 3, 2, 1}},
+		{"MaxKeyLength ArrayKeys long", Reader{MaxKeyLength: 3, ArrayKeys: true}, "k[a]bcdefghijk = 1", LimitError{"MaxKeyLength", 3, 1, 1}},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			for _, read := range []func() error{
+				func() error { return c.dec.Read(plainReader{strings.NewReader(c.src)}, Values{}) },
+				func() error { return c.dec.readBytes([]byte(c.src), Values{}) },
+			} {
+				var le *LimitError
+				if err := read(); !errors.As(err, &le) {
+					t.Fatalf("err(%T) = %v; want *LimitError", err, err)
+				} else if *le != c.want {
+					t.Errorf("err = %#v; want %#v", *le, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReader_limitsNotExceeded(t *testing.T) {
+	const src = "[a b]\nkey = value\nkey = value\n"
+	dec := Reader{
+		MaxBytes:        int64(len(src)),
+		MaxKeyLength:    len("a.b.key"),
+		MaxValueLength:  len("value"),
+		MaxKeys:         2,
+		MaxSectionDepth: 2,
+	}
+	testReadINIMatching(t, &dec, src, Values{"a.b.key": {"value", "value"}})
+}
+
+func TestReader_limitsArrayKeys(t *testing.T) {
+	// Keys are limited by their length once subscripts are rewritten
+	dec := Reader{ArrayKeys: true, MaxKeyLength: len("a.b.k.x")}
+	testReadINIMatching(t, &dec, "[a b]\nk[] = 1\nk[x] = 2\n", Values{"a.b.k": {"1"}, "a.b.k.x": {"2"}})
+}
+
+func TestReadContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	buf.WriteString("a = 1\n")
+	if err := ReadContext(ctx, &buf, Values{}); err != context.Canceled {
+		t.Errorf("err = %v; want %v", err, context.Canceled)
+	}
+
+	got := Values{}
+	if err := ReadContext(context.Background(), strings.NewReader("a = 1\n"), got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Values{"a": {"1"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
+
+// cancelRecorder cancels a context after recording a value.
+type cancelRecorder struct {
+	Values
+	cancel context.CancelFunc
+}
+
+func (c cancelRecorder) Add(key, value string) {
+	c.Values.Add(key, value)
+	c.cancel()
+}
+
+func TestReadContext_cancelDuringRead(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	dst := cancelRecorder{Values{}, cancel}
+	src := io.MultiReader(strings.NewReader("a = 1\nb = 2\n"), strings.NewReader("c = 3\n"))
+	if err := ReadContext(ctx, src, dst); err != context.Canceled {
+		t.Errorf("err = %v; want %v", err, context.Canceled)
+	}
+	if want := (Values{"a": {"1"}}); !reflect.DeepEqual(dst.Values, want) {
+		t.Errorf("got %v; want %v", dst.Values, want)
+	}
+}
+
+// endlessReader yields prefix followed by an endless run of the byte fill, calling onRead before
+// each read after the prefix.
+type endlessReader struct {
+	prefix string
+	fill   byte
+	onRead func()
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	if r.prefix != "" {
+		n := copy(p, r.prefix)
+		r.prefix = r.prefix[n:]
+		return n, nil
+	}
+	if r.onRead != nil {
+		r.onRead()
+	}
+	for i := range p {
+		p[i] = r.fill
+	}
+	return len(p), nil
+}
+
+func TestReader_limitsEndlessInput(t *testing.T) {
+	cases := []struct {
+		name   string
+		reader Reader
+		prefix string
+		want   LimitError
+	}{
+		{"Key", Reader{MaxKeyLength: 8}, "", LimitError{"MaxKeyLength", 8, 1, 1}},
+		{"Value", Reader{MaxValueLength: 8}, "k = ", LimitError{"MaxValueLength", 8, 1, 5}},
+		{"QuotedValue", Reader{MaxValueLength: 8}, "k = \"", LimitError{"MaxValueLength", 8, 1, 5}},
+		{"RawValue", Reader{MaxValueLength: 8}, "k = `", LimitError{"MaxValueLength", 8, 1, 5}},
+		{"Heredoc", Reader{MaxValueLength: 8, Heredoc: true}, "k = <<EOF\n", LimitError{"MaxValueLength", 8, 1, 5}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fill := byte('x')
+			if c.name == "Heredoc" {
+				fill = '\n'
+			}
+			src := &endlessReader{prefix: c.prefix, fill: fill}
+			err := c.reader.Read(src, Values{})
+			if le, ok := err.(*LimitError); !ok || *le != c.want {
+				t.Errorf("err = %v; want %v", err, &c.want)
+			}
+		})
+	}
+}
+
+func TestReadContext_cancelDuringValue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := &endlessReader{prefix: "k = ", fill: 'x', onRead: cancel}
+	if err := ReadContext(ctx, src, Values{}); err != context.Canceled {
+		t.Errorf("err = %v; want %v", err, context.Canceled)
+	}
+}
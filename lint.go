@@ -0,0 +1,315 @@
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// LintRule is a set of checks performed by Lint. Rules may be combined with bitwise OR.
+type LintRule uint
+
+const (
+	// LintDuplicateKeys reports keys that are set to the same value more than once.
+	LintDuplicateKeys LintRule = 1 << iota
+	// LintOverriddenKeys reports keys that are set to a different value later in the file. Array
+	// keys (e.g., "k[]") are not reported.
+	LintOverriddenKeys
+	// LintEmptyValues reports keys with an empty, unquoted value (e.g., "k =").
+	LintEmptyValues
+	// LintFlagKeys reports keys without a value where a value was probably intended: keys
+	// containing a colon (e.g., "port:80") and keys that are given a value elsewhere.
+	LintFlagKeys
+	// LintTrailingWhitespace reports lines ending in whitespace.
+	LintTrailingWhitespace
+	// LintMixedComments reports comments that do not begin with the same character as the first
+	// comment in the file.
+	LintMixedComments
+	// LintEmptySections reports section headings that are not followed by any keys.
+	LintEmptySections
+	// LintTruncatedValues reports unquoted values immediately followed by a comment, as in
+	// "url = http://host/#anchor", where the value was likely meant to include the comment.
+	LintTruncatedValues
+	// LintInvalidUTF8 reports lines containing bytes that are not valid UTF-8.
+	LintInvalidUTF8
+
+	// LintAll is every lint rule.
+	LintAll = LintDuplicateKeys | LintOverriddenKeys | LintEmptyValues | LintFlagKeys |
+		LintTrailingWhitespace | LintMixedComments | LintEmptySections | LintTruncatedValues |
+		LintInvalidUTF8
+)
+
+var lintRuleNames = [...]string{
+	"duplicate-keys",
+	"overridden-keys",
+	"empty-values",
+	"flag-keys",
+	"trailing-whitespace",
+	"mixed-comments",
+	"empty-sections",
+	"truncated-values",
+	"invalid-utf8",
+}
+
+// String returns the name of a single rule, such as "duplicate-keys", or the names of the rules
+// in a set separated by commas.
+func (r LintRule) String() string {
+	if r == 0 {
+		return "none"
+	}
+
+	var names []string
+	for rest := r; rest != 0; rest &= rest - 1 {
+		i := bits.TrailingZeros(uint(rest))
+		if i < len(lintRuleNames) {
+			names = append(names, lintRuleNames[i])
+		} else {
+			names = append(names, fmt.Sprintf("LintRule(%#x)", uint(1)<<i))
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseLintRules parses a comma-separated list of rule names, as returned by LintRule.String, and
+// returns the set of rules. The name "all" is every rule.
+func ParseLintRules(s string) (LintRule, error) {
+	var rules LintRule
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "all" {
+			rules |= LintAll
+			continue
+		}
+
+		found := false
+		for i, rn := range lintRuleNames {
+			if rn == name {
+				rules, found = rules|1<<i, true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("ini: unknown lint rule %q", name)
+		}
+	}
+	return rules, nil
+}
+
+// LintWarning is a problem found by Lint.
+type LintWarning struct {
+	// Rule is the rule that found the problem.
+	Rule LintRule
+	// Key is the key the warning is about, if any.
+	Key string
+	// Line and Col are the position of the problem, starting from 1. Col counts runes.
+	Line, Col int
+	// Message describes the problem.
+	Message string
+}
+
+func (w LintWarning) String() string {
+	return fmt.Sprintf("%d:%d: %s (%v)", w.Line, w.Col, w.Message, w.Rule)
+}
+
+// Lint checks doc for problems described by rules and returns a warning for each problem found,
+// ordered by position.
+func Lint(doc *Document, rules LintRule) []LintWarning {
+	l := linter{doc: doc, rules: rules}
+	l.keys()
+	l.sections()
+	l.comments()
+	l.lines()
+
+	sort.SliceStable(l.warnings, func(i, j int) bool {
+		a, b := l.warnings[i], l.warnings[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return l.warnings
+}
+
+// linter holds the state of a call to Lint.
+type linter struct {
+	doc      *Document
+	rules    LintRule
+	warnings []LintWarning
+}
+
+// warn adds a warning for rule at line and col if rule is enabled.
+func (l *linter) warn(rule LintRule, key string, line, col int, format string, args ...interface{}) {
+	if l.rules&rule == 0 {
+		return
+	}
+	l.warnings = append(l.warnings, LintWarning{
+		Rule:    rule,
+		Key:     key,
+		Line:    line,
+		Col:     col,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// keys checks the Document's keys and values.
+func (l *linter) keys() {
+	doc := l.doc
+	seen := make(map[string][]*docEntry, len(doc.entries))
+	for i := range doc.entries {
+		e := &doc.entries[i]
+		key, value := e.key.Text, doc.value(e)
+
+		if prev := seen[key]; len(prev) > 0 && !strings.HasSuffix(e.key.Raw, "[]") {
+			dup := (*docEntry)(nil)
+			for _, p := range prev {
+				if doc.value(p) == value {
+					dup = p
+					break
+				}
+			}
+			if dup != nil {
+				l.warn(LintDuplicateKeys, key, e.key.Line, e.key.Col,
+					"key %q is set to the same value on line %d", key, dup.key.Line)
+			} else {
+				last := prev[len(prev)-1]
+				l.warn(LintOverriddenKeys, key, e.key.Line, e.key.Col,
+					"key %q overrides the value set on line %d", key, last.key.Line)
+			}
+		}
+		seen[key] = append(seen[key], e)
+
+		switch {
+		case !e.hasValue:
+			if strings.Contains(e.key.Raw, ":") {
+				l.warn(LintFlagKeys, key, e.key.Line, e.key.Col,
+					"key %q has no value; use = instead of :", key)
+			}
+		case e.value.Raw == "":
+			l.warn(LintEmptyValues, key, e.key.Line, e.key.Col, "key %q has an empty value", key)
+		}
+	}
+
+	// Flag keys that are given values elsewhere
+	for i := range doc.entries {
+		e := &doc.entries[i]
+		if e.hasValue || strings.Contains(e.key.Raw, ":") {
+			continue
+		}
+		for _, o := range seen[e.key.Text] {
+			if o.hasValue {
+				l.warn(LintFlagKeys, e.key.Text, e.key.Line, e.key.Col,
+					"key %q has no value but is set to a value on line %d", e.key.Text, o.key.Line)
+				break
+			}
+		}
+	}
+}
+
+// sections checks for sections without keys.
+func (l *linter) sections() {
+	counts := make([]int, len(l.doc.sections))
+	for _, e := range l.doc.entries {
+		counts[e.section]++
+	}
+	for i, s := range l.doc.sections[1:] {
+		if counts[i+1] == 0 {
+			l.warn(LintEmptySections, s.name, s.heading.Line, s.heading.Col,
+				"section %q has no keys", s.name)
+		}
+	}
+}
+
+// comments checks comments for mixed comment characters and values truncated by comments.
+func (l *linter) comments() {
+	if l.rules&(LintMixedComments|LintTruncatedValues) == 0 {
+		return
+	}
+
+	var (
+		first    byte
+		valueEnd int64 = -1
+		valueRaw string
+		valueKey string
+		s        = l.doc.cfg.NewScanner(bytes.NewReader(l.doc.src))
+		lastKey  string
+	)
+	for s.Next() {
+		tok := s.Token()
+		switch tok.Kind {
+		case Key:
+			lastKey = tok.Text
+		case Value:
+			valueEnd, valueRaw, valueKey = tok.Offset+int64(len(tok.Raw)), tok.Raw, lastKey
+		case Comment:
+			c := tok.Raw[0]
+			if tok.Offset == valueEnd && valueRaw != "" && valueRaw[0] != rQuote && valueRaw[0] != rRawQuote {
+				// Likely not meant to be a comment, so don't check its comment character
+				l.warn(LintTruncatedValues, valueKey, tok.Line, tok.Col,
+					"value of %q ends at %q; quote the value if %q is part of it", valueKey, c, c)
+				continue
+			}
+
+			if first == 0 {
+				first = c
+			} else if c != first {
+				l.warn(LintMixedComments, "", tok.Line, tok.Col,
+					"comment begins with %q instead of %q", c, first)
+			}
+		}
+	}
+}
+
+// lines checks each line for trailing whitespace and invalid UTF-8.
+func (l *linter) lines() {
+	if l.rules&(LintTrailingWhitespace|LintInvalidUTF8) == 0 {
+		return
+	}
+
+	// Lines inside multi-line values may end in whitespace
+	var spans [][2]int
+	for _, e := range l.doc.entries {
+		if e.hasValue && strings.Contains(e.value.Raw, "\n") {
+			spans = append(spans, [2]int{int(e.value.Offset), tokenEnd(e.value)})
+		}
+	}
+	inValue := func(off int) bool {
+		for _, sp := range spans {
+			if off >= sp[0] && off < sp[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	src := l.doc.src
+	for line, start := 1, 0; start < len(src); line++ {
+		end := bytes.IndexByte(src[start:], rNewline)
+		if end == -1 {
+			end = len(src)
+		} else {
+			end += start
+		}
+		text := bytes.TrimSuffix(src[start:end], []byte{'\r'})
+
+		if trimmed := bytes.TrimRight(text, " \t"); len(trimmed) < len(text) && !inValue(start+len(trimmed)) {
+			l.warn(LintTrailingWhitespace, "", line, utf8.RuneCount(trimmed)+1, "line ends in whitespace")
+		}
+		if !utf8.Valid(text) {
+			i := 0
+			for i < len(text) {
+				r, size := utf8.DecodeRune(text[i:])
+				if r == utf8.RuneError && size == 1 {
+					break
+				}
+				i += size
+			}
+			l.warn(LintInvalidUTF8, "", line, utf8.RuneCount(text[:i])+1,
+				"line contains invalid UTF-8 byte %#x", text[i])
+		}
+
+		start = end + 1
+	}
+}
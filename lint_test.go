@@ -0,0 +1,72 @@
+package ini
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	const src = "; comment\n" +
+		"url = http://host/#anchor\n" +
+		"# other comment \n" +
+		"[empty]\n" +
+		"[s]\n" +
+		"k = 1\n" +
+		"k = 1\n" +
+		"k = 2\n" +
+		"e =\n" +
+		"q = \"\"\n" +
+		"port:80\n" +
+		"f\n" +
+		"f = yes\n" +
+		"a[] = 1\n" +
+		"a[] = 2\n" +
+		"h = \"multi  \n" +
+		"line\"\n" +
+		"bad = \xff\n"
+
+	dec := Reader{ArrayKeys: true}
+	doc, err := dec.ParseDocument([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []LintWarning{
+		{LintTruncatedValues, "url", 2, 19, `value of "url" ends at '#'; quote the value if '#' is part of it`},
+		{LintMixedComments, "", 3, 1, `comment begins with '#' instead of ';'`},
+		{LintTrailingWhitespace, "", 3, 16, "line ends in whitespace"},
+		{LintEmptySections, "empty", 4, 1, `section "empty" has no keys`},
+		{LintDuplicateKeys, "s.k", 7, 1, `key "s.k" is set to the same value on line 6`},
+		{LintOverriddenKeys, "s.k", 8, 1, `key "s.k" overrides the value set on line 7`},
+		{LintEmptyValues, "s.e", 9, 1, `key "s.e" has an empty value`},
+		{LintFlagKeys, "s.port:80", 11, 1, `key "s.port:80" has no value; use = instead of :`},
+		{LintFlagKeys, "s.f", 12, 1, `key "s.f" has no value but is set to a value on line 13`},
+		{LintOverriddenKeys, "s.f", 13, 1, `key "s.f" overrides the value set on line 12`},
+		{LintInvalidUTF8, "", 18, 7, "line contains invalid UTF-8 byte 0xff"},
+	}
+	if got := Lint(doc, LintAll); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() =\n%v\nwant\n%v", got, want)
+	}
+
+	got := Lint(doc, LintEmptySections|LintInvalidUTF8)
+	if len(got) != 2 || got[0].Rule != LintEmptySections || got[1].Rule != LintInvalidUTF8 {
+		t.Errorf("Lint(empty-sections,invalid-utf8) = %v", got)
+	}
+}
+
+func TestLintRule_String(t *testing.T) {
+	if got, want := (LintDuplicateKeys | LintInvalidUTF8).String(), "duplicate-keys,invalid-utf8"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+
+	rules, err := ParseLintRules(LintAll.String())
+	if err != nil || rules != LintAll {
+		t.Errorf("ParseLintRules(%q) = %v, %v; want %v", LintAll.String(), rules, err, LintAll)
+	}
+	if rules, err := ParseLintRules("all"); err != nil || rules != LintAll {
+		t.Errorf("ParseLintRules(all) = %v, %v; want %v", rules, err, LintAll)
+	}
+	if _, err := ParseLintRules("empty-values,bogus"); err == nil {
+		t.Error("ParseLintRules(bogus) = nil; want error")
+	}
+}
@@ -0,0 +1,134 @@
+package ini
+
+import (
+	"sort"
+	"strings"
+)
+
+// Profiles resolves profile sections in Values. A profile section overlays a base section for one
+// profile (e.g., an environment) and is named by the base section, Delimiter, and the profile's
+// name, such that
+//
+//	[database]
+//	host = localhost
+//	port = 5432
+//
+//	[database:production]
+//	host = db.internal
+//
+// gives database.host = db.internal and database.port = 5432 for the profile "production". Only
+// the first segment of a section name may name a profile, so [database:production replica]
+// overlays [database replica], but [remote "http://host:8080"] is an ordinary section.
+type Profiles struct {
+	// Separator is the string between a section name and a key. If Separator is None, keys have no
+	// sections, and so no profiles. If Separator is the empty string, it defaults to "." (period).
+	Separator string
+	// Delimiter is the string between the name of a base section and a profile. If Delimiter is
+	// the empty string, it defaults to ":" (colon).
+	Delimiter string
+}
+
+// ProfileOverride describes a key whose values were replaced by a profile section.
+type ProfileOverride struct {
+	// Key is the key as it appears in the base section (e.g., "database.host").
+	Key string
+	// Profile is the profile whose section replaced the values.
+	Profile string
+	// Old and New are the values of Key before and after they were replaced.
+	Old, New []string
+}
+
+// ForProfile returns a copy of the receiver with the profile sections of the given profiles merged
+// over their base sections, using the default separator and delimiter. See Profiles.ForProfile.
+func (v Values) ForProfile(profiles ...string) Values {
+	var p Profiles
+	out, _ := p.ForProfile(v, profiles...)
+	return out
+}
+
+func (p *Profiles) sep() string {
+	return separatorOrDefault(p.Separator)
+}
+
+func (p *Profiles) delim() string {
+	if p.Delimiter == "" {
+		return ":"
+	}
+	return p.Delimiter
+}
+
+// split returns the key in the base section and the profile of key, if key is in a profile
+// section. Only the first segment of the section name is considered.
+func (p *Profiles) split(key string) (base, profile string, ok bool) {
+	sep, delim := p.sep(), p.delim()
+	if sep == "" {
+		// Without a separator, keys have no sections
+		return "", "", false
+	}
+	end := strings.Index(key, sep)
+	if end == -1 {
+		return "", "", false
+	}
+
+	seg := key[:end]
+	if i := strings.LastIndex(seg, delim); i > 0 && i+len(delim) < len(seg) {
+		return key[:i] + key[end:], seg[i+len(delim):], true
+	}
+	return "", "", false
+}
+
+// ForProfile returns a copy of v in which the sections of the given profiles are merged over their
+// base sections and the sections of all other profiles are dropped. A key in a profile section
+// replaces the values of the same key in the base section. Profiles are merged in the order given,
+// so later profiles take precedence over earlier ones.
+//
+// ForProfile also returns the keys whose values were replaced, in the order they were replaced.
+// Keys that a profile section adds to its base section are not reported.
+func (p *Profiles) ForProfile(v Values, profiles ...string) (Values, []ProfileOverride) {
+	rank := make(map[string]int, len(profiles))
+	for i, name := range profiles {
+		rank[name] = i
+	}
+
+	type overlay struct {
+		key, profile string
+		rank         int
+		values       []string
+	}
+
+	dst := make(Values, len(v))
+	var overlays []overlay
+	for k, vs := range v {
+		base, profile, ok := p.split(k)
+		if !ok {
+			dst[k] = append([]string(nil), vs...)
+			continue
+		}
+		if r, active := rank[profile]; active {
+			overlays = append(overlays, overlay{key: base, profile: profile, rank: r, values: vs})
+		}
+	}
+
+	sort.Slice(overlays, func(i, j int) bool {
+		a, b := overlays[i], overlays[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.key < b.key
+	})
+
+	var overrides []ProfileOverride
+	for _, o := range overlays {
+		values := append([]string(nil), o.values...)
+		if old, ok := dst[o.key]; ok {
+			overrides = append(overrides, ProfileOverride{
+				Key:     o.key,
+				Profile: o.profile,
+				Old:     old,
+				New:     values,
+			})
+		}
+		dst[o.key] = values
+	}
+	return dst, overrides
+}
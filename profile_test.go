@@ -0,0 +1,129 @@
+package ini
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValues_ForProfile(t *testing.T) {
+	v, err := ReadINI([]byte(`
+name = app
+[database]
+host = localhost
+port = 5432
+[database:production]
+host = db.internal
+pool = 20
+[database:staging]
+host = staging.internal
+[database:production replica]
+host = replica.internal
+[cache]
+ttl = 60
+[cache:staging]
+ttl = 5
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Values{
+		"name":                  {"app"},
+		"database.host":         {"db.internal"},
+		"database.port":         {"5432"},
+		"database.pool":         {"20"},
+		"database.replica.host": {"replica.internal"},
+		"cache.ttl":             {"60"},
+	}
+	if got := v.ForProfile("production"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ForProfile(production) = %v; want %v", got, want)
+	}
+
+	var p Profiles
+	got, overrides := p.ForProfile(v, "production", "staging")
+	want["database.host"] = []string{"staging.internal"}
+	want["cache.ttl"] = []string{"5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ForProfile(production, staging) = %v; want %v", got, want)
+	}
+	wantOverrides := []ProfileOverride{
+		{Key: "database.host", Profile: "production", Old: []string{"localhost"}, New: []string{"db.internal"}},
+		{Key: "cache.ttl", Profile: "staging", Old: []string{"60"}, New: []string{"5"}},
+		{Key: "database.host", Profile: "staging", Old: []string{"db.internal"}, New: []string{"staging.internal"}},
+	}
+	if !reflect.DeepEqual(overrides, wantOverrides) {
+		t.Errorf("overrides = %+v; want %+v", overrides, wantOverrides)
+	}
+
+	// No profiles drops all profile sections
+	got = v.ForProfile()
+	if want := (Values{
+		"name":          {"app"},
+		"database.host": {"localhost"},
+		"database.port": {"5432"},
+		"cache.ttl":     {"60"},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("ForProfile() = %v; want %v", got, want)
+	}
+
+	// The result does not share values with the receiver
+	got["database.port"][0] = "0"
+	if v.Get("database.port") != "5432" {
+		t.Error("ForProfile() result shares values with the receiver")
+	}
+}
+
+func TestProfiles_delimiter(t *testing.T) {
+	v := Values{
+		"db/host":      {"localhost"},
+		"db@prod/host": {"db.internal"},
+		"@prod/x":      {"1"},
+		"db@/x":        {"2"},
+		"key@prod":     {"3"},
+	}
+	p := Profiles{Separator: "/", Delimiter: "@"}
+	got, overrides := p.ForProfile(v, "prod")
+	want := Values{
+		"db/host":  {"db.internal"},
+		"@prod/x":  {"1"},
+		"db@/x":    {"2"},
+		"key@prod": {"3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ForProfile() = %v; want %v", got, want)
+	}
+	if len(overrides) != 1 || overrides[0].Key != "db/host" {
+		t.Errorf("overrides = %+v; want db/host", overrides)
+	}
+}
+
+func TestProfiles_quotedSection(t *testing.T) {
+	v, err := ReadINI([]byte(`
+[remote "http://host:8080"]
+url = x
+[remote:production "http://host:8080"]
+url = y
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first segment of a section name may name a profile, so a colon in a later
+	// segment does not make a profile section
+	want := Values{"remote.http://host:8080.url": {"y"}}
+	if got := v.ForProfile("production"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ForProfile(production) = %v; want %v", got, want)
+	}
+	want = Values{"remote.http://host:8080.url": {"x"}}
+	if got := v.ForProfile(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ForProfile() = %v; want %v", got, want)
+	}
+}
+
+func TestProfiles_noSeparator(t *testing.T) {
+	v := Values{"db:prod.host": {"db.internal"}, "db.host": {"localhost"}}
+	p := Profiles{Separator: None}
+	if got, _ := p.ForProfile(v, "prod"); !reflect.DeepEqual(got, v) {
+		t.Errorf("ForProfile() = %v; want %v", got, v)
+	}
+}
@@ -0,0 +1,292 @@
+package ini
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// PropertiesDecoder is a Reader for Java .properties files. Keys are case-sensitive.
+var PropertiesDecoder = Reader{
+	Casing:     CaseSensitive,
+	Properties: true,
+}
+
+// ReadProperties reads a Java .properties file from b, writing the results to the out Values. If
+// out is nil, a new Values is allocated to store the results.
+//
+// ReadProperties is a convenience function for calling
+// PropertiesDecoder.Read(bytes.NewReader(b), out), except that it reads directly from b.
+func ReadProperties(b []byte, out Values) (Values, error) {
+	if out == nil {
+		out = make(Values)
+	}
+	if err := PropertiesDecoder.readBytes(b, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func isPropSpace(r rune) bool { return r == ' ' || r == '\t' || r == '\f' }
+
+// dropCR removes carriage returns from values, where they can only precede a newline.
+func dropCR(r rune) rune {
+	if r == '\r' {
+		return -1
+	}
+	return r
+}
+
+// nextMark returns the position of the rune following the current one.
+func (d *decoder) nextMark() mark {
+	off := d.pos
+	if d.havenext {
+		off -= int64(d.nextSize)
+	}
+	return mark{off: off, line: d.line, col: d.col + 1}
+}
+
+// skipPropSpace skips whitespace following the current rune, up to the end of the line.
+func (d *decoder) skipPropSpace() error {
+	for {
+		if r, _, err := d.peekRune(); err != nil || !isPropSpace(r) {
+			return nil
+		}
+		if err := d.skip(); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *decoder) readPropElem() (nextfunc, error) {
+	switch d.current {
+	case rHash, '!':
+		return d.readComment()
+	default:
+		return d.readPropKey()
+	}
+}
+
+// readPropKey reads a key, starting with the current rune, up to the separator between it and its
+// value.
+func (d *decoder) readPropKey() (next nextfunc, err error) {
+	start := d.mark()
+	d.keyStart = start
+	d.limitBuffer("MaxKeyLength", d.limits.MaxKeyLength)
+	for {
+		if d.current == rEscape {
+			err = d.readPropEscape()
+		} else if setPropKeyEnd.Contains(d.current) {
+			break
+		} else if d.casefn != nil {
+			d.buffer.WriteRune(d.casefn(d.current))
+		} else {
+			d.buffer.WriteRune(d.current)
+		}
+
+		if err == nil {
+			err = d.readUntil(setPropKeyEnd, true, d.casefn)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if max := d.limits.MaxKeyLength; max > 0 && d.buffer.Len() > max {
+		return nil, d.limitErr("MaxKeyLength", int64(max), start)
+	}
+
+	key := d.internKey(d.buffer.Bytes())
+	d.token(Key, key, start, d.offset)
+	if err == io.EOF {
+		return nil, d.add(key, "")
+	}
+
+	d.key = key
+	d.buffer.Reset()
+	d.limitBuffer("MaxValueLength", d.limits.MaxValueLength)
+
+	switch d.current {
+	case rNewline:
+		return (*decoder).readElem, d.add(key, "")
+	case rEquals, ':':
+	default:
+		// Whitespace may be followed by = or :
+		if err = d.skipPropSpace(); err != nil {
+			return nil, err
+		}
+		if r, _, perr := d.peekRune(); perr == nil && (r == rEquals || r == ':') {
+			if err = d.skip(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return (*decoder).readPropValue, d.skipPropSpace()
+}
+
+// readPropValue reads a value, starting after the current rune, up to the end of its line.
+func (d *decoder) readPropValue() (next nextfunc, err error) {
+	start := d.nextMark()
+	d.valueStart = start
+	for {
+		if err = d.readUntil(setPropValueEnd, true, dropCR); err != nil || d.current != rEscape {
+			break
+		} else if err = d.readPropEscape(); err != nil {
+			break
+		}
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	end := d.offset
+	if d.scan && end > start.off && d.raw[end-1-d.rawOff] == '\r' {
+		end--
+	}
+	if aerr := d.addValue(d.buffer.String(), start, end); aerr != nil {
+		return nil, aerr
+	}
+	defer stopOnEOF(&next, &err)
+	return (*decoder).readElem, err
+}
+
+// readPropEscape reads the escape sequence following a backslash and writes the rune it produces
+// to the buffer. A backslash at the end of a line continues the line, skipping the leading
+// whitespace of the next line. A backslash at the end of input is ignored.
+func (d *decoder) readPropEscape() error {
+	r, _, err := d.nextRune()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return d.propEscape(r)
+}
+
+// propEscape writes the rune produced by the escape sequence beginning with r, following a
+// backslash.
+func (d *decoder) propEscape(r rune) (err error) {
+	switch r {
+	case '\r':
+		if next, _, perr := d.peekRune(); perr == nil && next == rNewline {
+			if err = d.skip(); err != nil {
+				return err
+			}
+		}
+		return d.skipPropSpace()
+	case rNewline:
+		return d.skipPropSpace()
+	case 't', 'n', 'r', 'f':
+		d.buffer.WriteRune(escaped(r))
+		return nil
+	case 'u':
+	default:
+		d.buffer.WriteRune(r)
+		return nil
+	}
+
+	if r, err = d.readHexCode(4); err != nil {
+		return err
+	} else if !utf16.IsSurrogate(r) {
+		d.buffer.WriteRune(r)
+		return nil
+	}
+
+	// Characters outside the BMP are written as UTF-16 surrogate pairs (e.g., \ud83d\ude00)
+	if next, _, perr := d.peekRune(); perr != nil || next != rEscape {
+		d.buffer.WriteRune(utf8.RuneError)
+		return nil
+	}
+	if err = d.skip(); err != nil {
+		return err
+	}
+
+	next, _, err := d.nextRune()
+	if err == io.EOF {
+		d.buffer.WriteRune(utf8.RuneError)
+		return nil
+	} else if err != nil {
+		return err
+	} else if next != 'u' {
+		d.buffer.WriteRune(utf8.RuneError)
+		return d.propEscape(next)
+	}
+
+	low, err := d.readHexCode(4)
+	if err != nil {
+		return err
+	}
+	if pair := utf16.DecodeRune(r, low); pair != utf8.RuneError {
+		d.buffer.WriteRune(pair)
+	} else {
+		d.buffer.WriteRune(utf8.RuneError)
+		d.buffer.WriteRune(low)
+	}
+	return nil
+}
+
+// WriteProperties writes v to w as a Java .properties file, one key=value line per value in sorted
+// key order. As with java.util.Properties, the output is ASCII: characters that are not printable
+// ASCII are written as \uXXXX escapes, and characters with special meaning are escaped with a
+// backslash. Keys without values are not written. WriteProperties returns an error wrapping
+// ErrInvalidUTF8 if a key or value is not valid UTF-8, since it cannot be escaped without loss.
+func WriteProperties(w io.Writer, v Values) error {
+	bw := bufio.NewWriter(w)
+	for _, k := range v.sortedKeys() {
+		if !utf8.ValidString(k) {
+			return fmt.Errorf("ini: cannot write key %q to a properties file: %w", k, ErrInvalidUTF8)
+		}
+		for _, value := range v[k] {
+			if !utf8.ValidString(value) {
+				return fmt.Errorf("ini: cannot write value of %q to a properties file: %w", k, ErrInvalidUTF8)
+			}
+			bw.WriteString(propString(k, true))
+			bw.WriteByte(rEquals)
+			bw.WriteString(propString(value, false))
+			bw.WriteByte(rNewline)
+		}
+	}
+	return bw.Flush()
+}
+
+// propString returns s escaped for a properties file. If key is true, all spaces are escaped;
+// otherwise, only a leading space is.
+func propString(s string, key bool) string {
+	var w strings.Builder
+	w.Grow(len(s))
+	for i, r := range s {
+		switch r {
+		case ' ':
+			if key || i == 0 {
+				w.WriteByte(rEscape)
+			}
+			w.WriteByte(' ')
+		case '\t':
+			w.WriteString(`\t`)
+		case '\n':
+			w.WriteString(`\n`)
+		case '\r':
+			w.WriteString(`\r`)
+		case '\f':
+			w.WriteString(`\f`)
+		case rEscape, rEquals, ':', rHash, '!':
+			w.WriteByte(rEscape)
+			w.WriteRune(r)
+		default:
+			if r >= 0x20 && r < 0x7f {
+				w.WriteRune(r)
+			} else if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				fmt.Fprintf(&w, `\u%04x\u%04x`, r1, r2)
+			} else {
+				fmt.Fprintf(&w, `\u%04x`, r)
+			}
+		}
+	}
+	return w.String()
+}
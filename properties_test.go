@@ -0,0 +1,144 @@
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadProperties(t *testing.T) {
+	const src = "# comment\n" +
+		"! also a comment\n" +
+		"  indented = value\n" +
+		"colon:value\n" +
+		"space value with spaces  \n" +
+		"equals\t=\t value\n" +
+		"space\\ key = \\ leading\n" +
+		"empty=\n" +
+		"flag\n" +
+		"=no key\n" +
+		"escapes = \\t\\n\\r\\f\\u00e9\\\\\\=\\q\n" +
+		"surrogates = \\ud83d\\ude00 \\ud83d\\n\n" +
+		"continued = a, \\\n" +
+		"            b, \\\r\n" +
+		"\t\t    c\r\n" +
+		"multi\\\n  line\\ key = v\n" +
+		"[section] = not a section\n" +
+		"semi;colon = 1\n" +
+		"latin1 = caf\xe9\n" +
+		"dup = 1\n" +
+		"dup = 2\n" +
+		"last = \\"
+	want := Values{
+		"indented":      {"value"},
+		"colon":         {"value"},
+		"space":         {"value with spaces  "},
+		"equals":        {"value"},
+		"space key":     {" leading"},
+		"empty":         {""},
+		"flag":          {""},
+		"":              {"no key"},
+		"escapes":       {"\t\n\r\fé\\=q"},
+		"surrogates":    {"😀 \uFFFD\n"},
+		"continued":     {"a, b, c"},
+		"multiline key": {"v"},
+		"[section]":     {"not a section"},
+		"semi;colon":    {"1"},
+		"latin1":        {"café"},
+		"dup":           {"1", "2"},
+		"last":          {""},
+	}
+	testReadINIMatching(t, &PropertiesDecoder, src, want)
+
+	got, err := ReadProperties([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadProperties() = %q; want %q", got, want)
+	}
+
+	got = Values{}
+	if err := PropertiesDecoder.Read(iotest.OneByteReader(strings.NewReader("k = \xe9\xe8\n")), got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Values{"k": {"éè"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %q; want %q", got, want)
+	}
+}
+
+func TestReadProperties_errors(t *testing.T) {
+	for _, src := range []string{
+		"k = \\u12",
+		"k = \\u12x4",
+		"k\\u00 = v",
+	} {
+		err := testReadINIErrorWith(t, &PropertiesDecoder, src)
+		if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("Read(%q) error = %#v; want *SyntaxError", src, err)
+		}
+	}
+}
+
+func TestScanner_properties(t *testing.T) {
+	toks, err := scanAll(t, &PropertiesDecoder, strings.NewReader("! c\n\na b\\\n  c\r\nk\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Token{
+		{Kind: Comment, Text: " c", Raw: "! c", Line: 1, Col: 1, Offset: 0},
+		{Kind: Blank, Raw: "", Line: 2, Col: 1, Offset: 4},
+		{Kind: Key, Text: "a", Raw: "a", Line: 3, Col: 1, Offset: 5},
+		{Kind: Value, Text: "bc", Raw: "b\\\n  c", Line: 3, Col: 3, Offset: 7},
+		{Kind: Key, Text: "k", Raw: "k", Line: 5, Col: 1, Offset: 15},
+	}
+	if !reflect.DeepEqual(toks, want) {
+		t.Errorf("tokens =\n%#v\nwant\n%#v", toks, want)
+	}
+}
+
+func TestWriteProperties(t *testing.T) {
+	v := Values{
+		"a key":   {" value = x: y # z ! "},
+		"esc":     {"\\\t\n\r\f"},
+		"unicode": {"é😀"},
+		"multi":   {"1", "2"},
+		"none":    nil,
+		"":        {""},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProperties(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	want := "=\n" +
+		"a\\ key=\\ value \\= x\\: y \\# z \\! \n" +
+		"esc=\\\\\\t\\n\\r\\f\n" +
+		"multi=1\n" +
+		"multi=2\n" +
+		"unicode=\\u00e9\\ud83d\\ude00\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteProperties() =\n%s\nwant\n%s", got, want)
+	}
+
+	got, err := ReadProperties(buf.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(v, "none")
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("ReadProperties(WriteProperties()) = %q; want %q", got, v)
+	}
+}
+
+func TestWriteProperties_invalidUTF8(t *testing.T) {
+	for _, v := range []Values{{"k\xff": {"v"}}, {"k": {"a\x80b"}}} {
+		if err := WriteProperties(io.Discard, v); !errors.Is(err, ErrInvalidUTF8) {
+			t.Errorf("WriteProperties(%q) = %v; want %v", v, err, ErrInvalidUTF8)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ini
+
+import (
+	"fmt"
+	"os"
+)
+
+// Location is a position in INI input. Line and Col are 1-based, and Col counts runes, not bytes.
+type Location struct {
+	// File is the name of the input. It is empty if the name is not known (e.g., when reading
+	// from an io.Reader).
+	File string
+	Line int
+	Col  int
+}
+
+// String returns the location as "file:line:col", or "line:col" if there is no file name.
+func (l Location) String() string {
+	if l.File == "" {
+		return fmt.Sprintf("%d:%d", l.Line, l.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Col)
+}
+
+// location returns the Location of m in the decoder's input.
+func (d *decoder) location(m mark) Location {
+	return Location{File: d.file, Line: m.line, Col: m.col}
+}
+
+// ReadFile reads the INI file name as DefaultDecoder and conveys its values to dst.
+func ReadFile(name string, dst Recorder) error {
+	return DefaultDecoder.ReadFile(name, dst)
+}
+
+// ReadFile reads the INI file name and conveys its values to dst, as Read does. If dst is a
+// LocationRecorder, the locations it receives are in the named file.
+func (d *Reader) ReadFile(name string, dst Recorder) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var dec decoder
+	dec.reset(nil, d, dst, f)
+	dec.file = name
+	return dec.read()
+}
+
+// Tracked is a LocationRecorder that records values along with the location of each. Reading
+// several files into the same Tracked keeps the location of every value, so that, for example,
+// a value that fails validation can be reported along with the file and line that set it.
+//
+// The zero value of a Tracked is empty and ready to use.
+type Tracked struct {
+	// Values holds the recorded values. It is allocated by the first call to Add or AddAt if
+	// nil. Values modified other than by Add and AddAt no longer line up with their locations.
+	Values Values
+
+	locs map[string][]Location
+}
+
+// Add adds value to key at an unknown location.
+func (t *Tracked) Add(key, value string) {
+	t.AddAt(key, value, Location{})
+}
+
+// AddAt adds value to key, recording that it was set at loc.
+func (t *Tracked) AddAt(key, value string, loc Location) {
+	if t.Values == nil {
+		t.Values = make(Values)
+	}
+	if t.locs == nil {
+		t.locs = make(map[string][]Location)
+	}
+	t.Values.Add(key, value)
+	t.locs[key] = append(t.locs[key], loc)
+}
+
+// Provenance returns the locations of key's values, in the same order as its values. The location
+// of a value added by Add is the zero Location.
+func (t *Tracked) Provenance(key string) []Location {
+	locs := t.locs[key]
+	if len(locs) == 0 {
+		return nil
+	}
+	return append([]Location(nil), locs...)
+}
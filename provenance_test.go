@@ -0,0 +1,88 @@
+package ini
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTracked(t *testing.T) {
+	const src = "a = 1\n[s]\n  b = \"2\"\n  flag\n  c =\n[s t]\na = 3"
+	var tr Tracked
+	if err := DefaultDecoder.Read(strings.NewReader(src), &tr); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]Location{
+		"a":      {{Line: 1, Col: 1}},
+		"s.b":    {{Line: 3, Col: 3}},
+		"s.flag": {{Line: 4, Col: 3}},
+		"s.c":    {{Line: 5, Col: 3}},
+		"s.t.a":  {{Line: 7, Col: 1}},
+	}
+	for key, locs := range want {
+		if got := tr.Provenance(key); !reflect.DeepEqual(got, locs) {
+			t.Errorf("Provenance(%q) = %v; want %v", key, got, locs)
+		}
+	}
+	if got := tr.Provenance("missing"); got != nil {
+		t.Errorf("Provenance(missing) = %v; want nil", got)
+	}
+
+	tr.Add("a", "4")
+	if got, want := tr.Provenance("a"), []Location{{Line: 1, Col: 1}, {}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Provenance(a) = %v; want %v", got, want)
+	}
+}
+
+func TestReader_ReadFile(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.ini")
+	local := filepath.Join(dir, "local.ini")
+	if err := os.WriteFile(base, []byte("[s]\nk = 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, []byte("; override\n\n[s]\n\tk = 2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var tr Tracked
+	for _, name := range []string{base, local} {
+		if err := ReadFile(name, &tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []Location{{base, 2, 1}, {local, 4, 2}}
+	if got := tr.Provenance("s.k"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Provenance(s.k) = %v; want %v", got, want)
+	}
+	if got, want := want[1].String(), local+":4:2"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+	if got, want := (Location{Line: 1, Col: 2}).String(), "1:2"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+
+	if err := ReadFile(filepath.Join(dir, "missing.ini"), &tr); !os.IsNotExist(err) {
+		t.Errorf("err = %v; want not exist", err)
+	}
+}
+
+func TestReader_ReadFileSyntaxError(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "bad.ini")
+	if err := os.WriteFile(name, []byte("[s]\n= 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ReadFile(name, Values{})
+	se, ok := err.(*SyntaxError)
+	if !ok || se.File != name || se.Line != 2 || se.Col != 1 {
+		t.Fatalf("err = %#v; want *SyntaxError in %s at 2:1", err, name)
+	}
+	if want := "ini: syntax error at " + name + ":2:1: "; !strings.HasPrefix(se.Error(), want) {
+		t.Errorf("Error() = %q; want prefix %q", se.Error(), want)
+	}
+}
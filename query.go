@@ -0,0 +1,254 @@
+package ini
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Query is a compiled pattern that selects keys by their sections. A pattern is matched against a
+// key one section segment at a time, where segments are separated by the query's separator, so
+// that "remote.*.url" matches "remote.origin.url" but not "remote.origin.push.url".
+//
+// Within a pattern, * matches any part of a single segment, including none of it. A segment of
+// only ** matches zero or more whole segments. {a,b} matches any one of its comma-separated
+// alternatives, and a backslash matches the character following it, such as a literal * or {.
+//
+// Each *, **, and {...} in a pattern captures the text it matched, in the order they appear in the
+// pattern. Segments matched by ** are captured joined by the separator.
+type Query struct {
+	pattern string
+	sep     string
+	segs    []querySeg
+}
+
+// querySeg is a single segment of a Query.
+type querySeg struct {
+	any   bool // any is true if the segment is ** and matches any number of segments
+	parts []queryPart
+}
+
+// queryPart is a literal, *, or set of alternatives in a segment of a Query.
+type queryPart struct {
+	kind queryPartKind
+	text string   // text is the text of a literal
+	alts []string // alts are the alternatives of a set
+}
+
+type queryPartKind int
+
+const (
+	queryLiteral queryPartKind = iota
+	queryStar
+	queryAlts
+)
+
+// Match is a key selected by a Query, along with its values and the text captured by each
+// wildcard and set of alternatives in the Query.
+type Match struct {
+	Key      string
+	Values   []string
+	Captures []string
+}
+
+// CompileQuery compiles pattern as a Query whose segments are separated by sep. If sep is None,
+// keys are matched as a single segment, and if sep is the empty string, it defaults to "."
+// (period). CompileQuery returns an error wrapping ErrInvalidQuery if pattern is malformed.
+func CompileQuery(pattern, sep string) (*Query, error) {
+	q := &Query{pattern: pattern, sep: separatorOrDefault(sep)}
+	if pattern == "" {
+		return nil, q.errorf("query is empty")
+	}
+
+	var seg querySeg
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			seg.parts = append(seg.parts, queryPart{kind: queryLiteral, text: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); {
+		switch c := pattern[i]; {
+		case q.sep != "" && strings.HasPrefix(pattern[i:], q.sep):
+			flush()
+			q.segs = append(q.segs, seg)
+			seg = querySeg{}
+			i += len(q.sep)
+		case c == '\\':
+			if i+1 == len(pattern) {
+				return nil, q.errorf("trailing backslash")
+			}
+			r, n := utf8.DecodeRuneInString(pattern[i+1:])
+			lit.WriteRune(r)
+			i += 1 + n
+		case c == '*':
+			flush()
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				end := i + 2
+				if len(seg.parts) > 0 || end < len(pattern) && (q.sep == "" || !strings.HasPrefix(pattern[end:], q.sep)) {
+					return nil, q.errorf("** must be a whole segment")
+				}
+				seg.any = true
+				i = end
+				continue
+			}
+			seg.parts = append(seg.parts, queryPart{kind: queryStar})
+			i++
+		case c == '{':
+			flush()
+			alts, n, err := q.parseAlts(pattern[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			seg.parts = append(seg.parts, queryPart{kind: queryAlts, alts: alts})
+			i += 1 + n
+		case c == '}':
+			return nil, q.errorf("unexpected }")
+		default:
+			lit.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	q.segs = append(q.segs, seg)
+	return q, nil
+}
+
+// parseAlts parses the alternatives of a set following its {, returning them and the length of
+// the set's text, including its closing }.
+func (q *Query) parseAlts(s string) (alts []string, n int, err error) {
+	var alt strings.Builder
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '}':
+			return append(alts, alt.String()), i + 1, nil
+		case c == ',':
+			alts = append(alts, alt.String())
+			alt.Reset()
+			i++
+		case c == '\\' && i+1 < len(s):
+			r, n := utf8.DecodeRuneInString(s[i+1:])
+			alt.WriteRune(r)
+			i += 1 + n
+		case c == '{' || c == '*':
+			return nil, 0, q.errorf("%c may not be used in {...}", c)
+		case q.sep != "" && strings.HasPrefix(s[i:], q.sep):
+			return nil, 0, q.errorf("separator %q may not be used in {...}", q.sep)
+		default:
+			alt.WriteByte(c)
+			i++
+		}
+	}
+	return nil, 0, q.errorf("unclosed {")
+}
+
+func (q *Query) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w %q: %s", ErrInvalidQuery, q.pattern, fmt.Sprintf(format, args...))
+}
+
+// String returns the pattern the Query was compiled from.
+func (q *Query) String() string {
+	return q.pattern
+}
+
+// Match returns whether key matches the Query and, if it does, the text captured by each wildcard
+// and set of alternatives. If a key can be matched more than one way, shorter captures are
+// preferred over longer ones, from left to right.
+func (q *Query) Match(key string) (captures []string, ok bool) {
+	names := []string{key}
+	if q.sep != "" {
+		names = strings.Split(key, q.sep)
+	}
+	captures, ok = q.match(q.segs, names, nil)
+	if ok && captures == nil {
+		captures = []string{}
+	}
+	return captures, ok
+}
+
+// match matches the segments segs against the key segments names, appending captures to caps.
+func (q *Query) match(segs []querySeg, names []string, caps []string) ([]string, bool) {
+	if len(segs) == 0 {
+		return caps, len(names) == 0
+	}
+
+	// Limit caps so that appending to it never overwrites captures of another attempt
+	caps = caps[:len(caps):len(caps)]
+	seg := segs[0]
+	if seg.any {
+		for n := 0; n <= len(names); n++ {
+			if c, ok := q.match(segs[1:], names[n:], append(caps, strings.Join(names[:n], q.sep))); ok {
+				return c, true
+			}
+		}
+		return nil, false
+	}
+
+	if len(names) == 0 {
+		return nil, false
+	}
+	caps, ok := matchParts(seg.parts, names[0], caps)
+	if !ok {
+		return nil, false
+	}
+	return q.match(segs[1:], names[1:], caps)
+}
+
+// matchParts matches the parts of a segment against the key segment s, appending captures to caps.
+func matchParts(parts []queryPart, s string, caps []string) ([]string, bool) {
+	if len(parts) == 0 {
+		return caps, s == ""
+	}
+
+	caps = caps[:len(caps):len(caps)]
+	switch p := parts[0]; p.kind {
+	case queryLiteral:
+		if !strings.HasPrefix(s, p.text) {
+			return nil, false
+		}
+		return matchParts(parts[1:], s[len(p.text):], caps)
+	case queryStar:
+		for i := 0; i <= len(s); i++ {
+			if i < len(s) && !utf8.RuneStart(s[i]) {
+				continue
+			}
+			if c, ok := matchParts(parts[1:], s[i:], append(caps, s[:i])); ok {
+				return c, true
+			}
+		}
+	default:
+		for _, alt := range p.alts {
+			if !strings.HasPrefix(s, alt) {
+				continue
+			}
+			if c, ok := matchParts(parts[1:], s[len(alt):], append(caps, alt)); ok {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Select returns a Match for each key in v that matches the Query, in sorted key order. The
+// values of each Match are the values in v and must not be modified.
+func (q *Query) Select(v Values) []Match {
+	var matches []Match
+	for _, k := range v.sortedKeys() {
+		if caps, ok := q.Match(k); ok {
+			matches = append(matches, Match{Key: k, Values: v[k], Captures: caps})
+		}
+	}
+	return matches
+}
+
+// Query compiles pattern with the default separator and returns the matching keys of the receiver,
+// as Query.Select does. See Query for the syntax of patterns.
+func (v Values) Query(pattern string) ([]Match, error) {
+	q, err := CompileQuery(pattern, "")
+	if err != nil {
+		return nil, err
+	}
+	return q.Select(v), nil
+}
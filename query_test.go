@@ -0,0 +1,79 @@
+package ini
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQuery_Match(t *testing.T) {
+	cases := []struct {
+		pattern, key string
+		sep          string
+		want         []string // nil if key does not match
+	}{
+		{"remote.*.url", "remote.origin.url", "", []string{"origin"}},
+		{"remote.*.url", "remote.origin.push.url", "", nil},
+		{"remote.*.url", "remote.url", "", nil},
+		{"backend.**.timeout", "backend.timeout", "", []string{""}},
+		{"backend.**.timeout", "backend.a.b.timeout", "", []string{"a.b"}},
+		{"backend.**.timeout", "backend.a.b.retry", "", nil},
+		{"**", "a.b", "", []string{"a.b"}},
+		{"server.{a,b}.port", "server.b.port", "", []string{"b"}},
+		{"server.{a,b}.port", "server.c.port", "", nil},
+		{"server.{a,ab}", "server.ab", "", []string{"ab"}},
+		{"web*.{http,https}_port", "web01.https_port", "", []string{"01", "https"}},
+		{"*-*", "a-b-c", "", []string{"a", "b-c"}},
+		{"x.*", "x.", "", []string{""}},
+		{`a\*.b`, "a*.b", "", []string{}},
+		{`a\*.b`, "ab.b", "", nil},
+		{"a/*/c", "a/b/c", "/", []string{"b"}},
+		{"a.*", "a.b.c", None, []string{"b.c"}},
+		{"db.host", "db.host", "", []string{}},
+		{"db.host", "db.hostname", "", nil},
+	}
+	for _, c := range cases {
+		q, err := CompileQuery(c.pattern, c.sep)
+		if err != nil {
+			t.Errorf("CompileQuery(%q) error = %v", c.pattern, err)
+			continue
+		}
+		got, ok := q.Match(c.key)
+		if ok != (c.want != nil) || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q.Match(%q) = %q, %t; want %q, %t", c.pattern, c.key, got, ok, c.want, c.want != nil)
+		}
+	}
+}
+
+func TestCompileQuery_errors(t *testing.T) {
+	for _, pattern := range []string{"", "a.**b", "a**.b", "a.{b", "a.{b.c}", "a.{*}", "a}", `a\`} {
+		if _, err := CompileQuery(pattern, ""); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("CompileQuery(%q) error = %v; want ErrInvalidQuery", pattern, err)
+		}
+	}
+}
+
+func TestValues_Query(t *testing.T) {
+	v := Values{
+		"remote.origin.url":   {"git@example.com:a"},
+		"remote.origin.fetch": {"+refs/heads/*"},
+		"remote.fork.url":     {"git@example.com:b", "git@example.com:c"},
+		"remote.url":          {"x"},
+		"core.bare":           {"false"},
+	}
+	got, err := v.Query("remote.*.url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Match{
+		{Key: "remote.fork.url", Values: []string{"git@example.com:b", "git@example.com:c"}, Captures: []string{"fork"}},
+		{Key: "remote.origin.url", Values: []string{"git@example.com:a"}, Captures: []string{"origin"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Query = %#v; want %#v", got, want)
+	}
+
+	if _, err := v.Query("remote.{"); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Query error = %v; want ErrInvalidQuery", err)
+	}
+}
@@ -0,0 +1,341 @@
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Render renders the template tmpl with the variables in vars and returns the result as a new
+// Document with the same configuration and encoding as tmpl.
+//
+// Placeholders of the form {{name}} in section headings, keys, and values are replaced with the
+// first value of name in vars. It is an error for a placeholder to name a variable that is not in
+// vars. A placeholder holding a double-quoted string is replaced with the string, so a literal
+// "{{" is written as {{"{{"}}. Placeholders in comments are not replaced. Because headings are
+// cased before they are rendered, placeholders in headings must be cased as the template's Reader
+// would case them.
+//
+// A section may be rendered conditionally or repeated with a directive in a comment on the same
+// line as its heading:
+//
+//	[database] ; @if env == prod
+//	host = db.internal
+//
+//	[backend {{host}}] ; @range host in hosts
+//	address = {{host}}:8080
+//
+// @if renders the section only if its condition holds. The condition is either a variable name,
+// which holds if the variable has a non-empty value, a name preceded by !, which holds if it does
+// not, or a comparison of a variable with == or != to a value, which may be double-quoted.
+// @range renders the section once for each value of a variable, with the name before "in" bound to
+// the value. A section is not rendered if the variable has no values. A ; or # outside of a
+// double-quoted value ends a directive, so "@if env == prod ; primary" compares env with prod.
+// Directive comments are not included in the output.
+//
+// Only text containing placeholders is rewritten, so comments and formatting are otherwise kept.
+// Rendered values are quoted as necessary for the template's dialect (INI, properties, or dotenv),
+// or kept in double quotes if they are double-quoted in an INI or dotenv template, and rendered
+// section names are written as new headings, so the output reads back as the rendered keys and
+// values. Render returns a *TemplateError if a rendered key cannot be written as it is, such as
+// when it contains whitespace, or if a rendered value does not read back as itself.
+func Render(tmpl *Document, vars Values) (*Document, error) {
+	r := renderer{tmpl: tmpl, vars: vars}
+	blocks, err := r.blocks()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	for i := range blocks {
+		if out, err = r.renderBlock(out, &blocks[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl.derive(out)
+}
+
+// renderer is the state of a single call to Render.
+type renderer struct {
+	tmpl *Document
+	vars Values
+
+	// name and value are the variable bound by @range, if bound is true
+	name, value string
+	bound       bool
+}
+
+// tmplBlock is a section of a template, from its heading up to the next heading, or the part of a
+// template preceding its first heading.
+type tmplBlock struct {
+	start, end int
+	tokens     []Token
+	line       int // line is the line of the block's heading, or 0 if it has none
+	directive  int // directive is the index of the comment holding the block's directive, or -1
+}
+
+// blocks splits the template into blocks.
+func (r *renderer) blocks() ([]tmplBlock, error) {
+	blocks := []tmplBlock{{directive: -1}}
+	s := r.tmpl.cfg.NewScanner(bytes.NewReader(r.tmpl.src))
+	for s.Next() {
+		tok := s.Token()
+		if tok.Kind == SectionStart {
+			blocks[len(blocks)-1].end = int(tok.Offset)
+			blocks = append(blocks, tmplBlock{start: int(tok.Offset), line: tok.Line, directive: -1})
+		}
+
+		b := &blocks[len(blocks)-1]
+		if tok.Kind == Comment && isDirective(tok.Text) {
+			if b.line != tok.Line || b.directive != -1 {
+				return nil, tmplErr(tok, fmt.Errorf("%w: directives must follow a section heading on the same line", ErrBadDirective))
+			}
+			b.directive = len(b.tokens)
+		}
+		b.tokens = append(b.tokens, tok)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	blocks[len(blocks)-1].end = len(r.tmpl.src)
+	return blocks, nil
+}
+
+// isDirective returns whether the comment text holds a directive.
+func isDirective(text string) bool {
+	fields := strings.Fields(text)
+	return len(fields) > 0 && (fields[0] == "@if" || fields[0] == "@range")
+}
+
+// renderBlock appends b to out, as many times as its directive calls for.
+func (r *renderer) renderBlock(out []byte, b *tmplBlock) ([]byte, error) {
+	if b.directive == -1 {
+		return r.render(out, b)
+	}
+
+	tok := b.tokens[b.directive]
+	text := strings.TrimSpace(directive(tok.Text))
+	fields := strings.Fields(text)
+	switch fields[0] {
+	case "@if":
+		ok, err := r.cond(text[len("@if"):])
+		if err != nil {
+			return nil, tmplErr(tok, err)
+		} else if !ok {
+			return out, nil
+		}
+		return r.render(out, b)
+	default: // @range
+		if len(fields) != 4 || fields[2] != "in" {
+			return nil, tmplErr(tok, fmt.Errorf("%w: expected @range NAME in LIST", ErrBadDirective))
+		}
+		defer func() { r.bound = false }()
+		var err error
+		for i, value := range r.vars[fields[3]] {
+			if i > 0 && !bytes.HasSuffix(out, []byte("\n\n")) {
+				// Separate repeated sections with a blank line
+				if !bytes.HasSuffix(out, []byte("\n")) {
+					out = append(out, rNewline)
+				}
+				out = append(out, rNewline)
+			}
+			r.name, r.value, r.bound = fields[1], value, true
+			if out, err = r.render(out, b); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+}
+
+// directive returns the directive in the comment text without any comment following it, which
+// begins at the first ; or # outside of a double-quoted string.
+func directive(text string) string {
+	quoted := false
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quoted && c == rEscape:
+			i++
+		case c == rQuote:
+			quoted = !quoted
+		case !quoted && (c == rSemicolon || c == rHash):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// cond evaluates the condition of an @if directive.
+func (r *renderer) cond(expr string) (bool, error) {
+	op := "=="
+	name, want, ok := strings.Cut(expr, op)
+	if !ok {
+		op = "!="
+		name, want, ok = strings.Cut(expr, op)
+	}
+	name, want = strings.TrimSpace(name), strings.TrimSpace(want)
+	negate := !ok && strings.HasPrefix(name, "!")
+	if negate {
+		name = strings.TrimSpace(name[1:])
+	}
+	if name == "" || strings.ContainsAny(name, " \t") || ok && want == "" {
+		return false, fmt.Errorf("%w: invalid condition %q", ErrBadDirective, strings.TrimSpace(expr))
+	}
+
+	value, _ := r.lookup(name)
+	if !ok {
+		return (value != "") != negate, nil
+	}
+	if strings.HasPrefix(want, `"`) {
+		unquoted, err := strconv.Unquote(want)
+		if err != nil {
+			return false, fmt.Errorf("%w: invalid string %s", ErrBadDirective, want)
+		}
+		want = unquoted
+	}
+	return (value == want) == (op == "=="), nil
+}
+
+// lookup returns the value of the variable name.
+func (r *renderer) lookup(name string) (string, bool) {
+	if r.bound && name == r.name {
+		return r.value, true
+	}
+	if vs := r.vars[name]; len(vs) > 0 {
+		return vs[0], true
+	}
+	return "", false
+}
+
+// render appends b to out once, replacing the placeholders in its headings, keys, and values.
+func (r *renderer) render(out []byte, b *tmplBlock) ([]byte, error) {
+	if len(out) > 0 && out[len(out)-1] != rNewline {
+		out = append(out, rNewline)
+	}
+
+	src := r.tmpl.src
+	pos := b.start
+	for i := range b.tokens {
+		tok := &b.tokens[i]
+		out = append(out, src[pos:tok.Offset]...)
+		pos = int(tok.Offset) + len(tok.Raw)
+
+		switch tok.Kind {
+		case SectionStart:
+			name, changed, err := r.expand(*tok, tok.Text)
+			if err != nil {
+				return nil, err
+			} else if !changed {
+				break
+			}
+			heading, err := r.tmpl.heading(name)
+			if err != nil {
+				return nil, tmplErr(*tok, fmt.Errorf("%w: section %q", err, name))
+			}
+			out = append(out, heading...)
+			continue
+		case Key:
+			key, changed, err := r.expand(*tok, tok.Raw)
+			if err != nil {
+				return nil, err
+			} else if !changed {
+				break
+			} else if !r.validKey(key) {
+				return nil, tmplErr(*tok, fmt.Errorf("%w: %q", ErrInvalidKey, key))
+			}
+			out = append(out, key...)
+			continue
+		case Value:
+			value, changed, err := r.expand(*tok, tok.Text)
+			if err != nil {
+				return nil, err
+			} else if !changed {
+				break
+			}
+			quoted := r.quote(*tok, value)
+			if !r.validValue(quoted, value) {
+				return nil, tmplErr(*tok, fmt.Errorf("%w: %q", ErrInvalidValue, value))
+			}
+			out = append(out, quoted...)
+			continue
+		case Comment:
+			if i == b.directive {
+				out = bytes.TrimRight(out, " \t")
+				continue
+			}
+		}
+		out = append(out, tok.Raw...)
+	}
+	return append(out, src[pos:b.end]...), nil
+}
+
+// expand returns s, read from tok, with its placeholders replaced, and whether s had any
+// placeholders.
+func (r *renderer) expand(tok Token, s string) (string, bool, error) {
+	i := strings.Index(s, "{{")
+	if i == -1 {
+		return s, false, nil
+	}
+
+	var b strings.Builder
+	for ; i != -1; i = strings.Index(s, "{{") {
+		b.WriteString(s[:i])
+		s = s[i+2:]
+		end := strings.Index(s, "}}")
+		if end == -1 {
+			return "", false, tmplErr(tok, UnclosedError('{'))
+		}
+
+		name := strings.TrimSpace(s[:end])
+		s = s[end+2:]
+		if strings.HasPrefix(name, `"`) {
+			// A quoted string is written as it is, so {{"{{"}} writes {{
+			lit, err := strconv.Unquote(name)
+			if err != nil {
+				return "", false, tmplErr(tok, fmt.Errorf("%w: invalid string %s", ErrBadDirective, name))
+			}
+			b.WriteString(lit)
+			continue
+		}
+		value, ok := r.lookup(name)
+		if !ok {
+			return "", false, tmplErr(tok, fmt.Errorf("%w: %q", ErrUndefinedVar, name))
+		}
+		b.WriteString(value)
+	}
+	b.WriteString(s)
+	return b.String(), true, nil
+}
+
+// validKey returns whether key reads back as itself when written unquoted.
+func (r *renderer) validKey(key string) bool {
+	doc, err := r.tmpl.cfg.ParseDocument([]byte(key + " = x\n"))
+	return err == nil && len(doc.entries) == 1 && len(doc.sections) == 1 &&
+		doc.entries[0].key.Raw == key && doc.value(&doc.entries[0]) == "x"
+}
+
+// quote returns value, read from tok, quoted for the template's dialect. Values double-quoted in
+// an INI or dotenv template are kept in double quotes.
+func (r *renderer) quote(tok Token, value string) string {
+	cfg := &r.tmpl.cfg
+	if cfg.Properties || !strings.HasPrefix(tok.Raw, string(rQuote)) {
+		return cfg.formatValue(value)
+	}
+	quoted := quoteString(value)
+	if cfg.DotEnv {
+		// quoteString never writes a $ other than those in value
+		quoted = strings.ReplaceAll(quoted, "$", `\$`)
+	}
+	return quoted
+}
+
+// validValue returns whether quoted reads back as value when written as the value of a key.
+func (r *renderer) validValue(quoted, value string) bool {
+	doc, err := r.tmpl.cfg.ParseDocument([]byte("k=" + quoted + "\n"))
+	return err == nil && len(doc.entries) == 1 && doc.value(&doc.entries[0]) == value
+}
+
+func tmplErr(tok Token, err error) *TemplateError {
+	return &TemplateError{Line: tok.Line, Col: tok.Col, Err: err}
+}
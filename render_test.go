@@ -0,0 +1,193 @@
+package ini
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	const tmpl = `; generated for {{host}}
+name = {{host}}
+
+[database] ; @if env == prod
+host = db.internal ; primary
+
+[database] ; @if env != "prod"
+host = localhost
+
+[debug]   ; @if !env
+verbose
+
+[backend {{b}}] ; @range b in backends
+address = {{b}}:8080
+{{b}}_weight = 1
+motd = "welcome to {{host}}"
+`
+	cases := []struct {
+		name string
+		vars Values
+		want string
+	}{
+		{"prod", Values{"host": {"web1"}, "env": {"prod"}, "backends": {"a", "b"}}, `; generated for {{host}}
+name = web1
+
+[database]
+host = db.internal ; primary
+
+[backend a]
+address = a:8080
+a_weight = 1
+motd = "welcome to web1"
+
+[backend b]
+address = b:8080
+b_weight = 1
+motd = "welcome to web1"
+`},
+		{"dev", Values{"host": {"dev;box"}, "env": {"dev"}}, `; generated for {{host}}
+name = "dev;box"
+
+[database]
+host = localhost
+
+`},
+		{"no env", Values{"host": {"h"}}, `; generated for {{host}}
+name = h
+
+[database]
+host = localhost
+
+[debug]
+verbose
+
+`},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			doc, err := ParseDocument([]byte(tmpl))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := Render(doc, c.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(out.Bytes()); got != c.want {
+				t.Errorf("Render =\n%s\nwant\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRender_values(t *testing.T) {
+	doc, err := ParseDocument([]byte("[s {{n}}] ; @range n in names\nk = {{n}}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Render(doc, Values{"names": {"a", "b c", "d\"e"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Values{"s.a.k": {"a"}, "s.b c.k": {"b c"}, "s.d\"e.k": {"d\"e"}}
+	if got := out.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %#v; want %#v", got, want)
+	}
+}
+
+func TestRender_escape(t *testing.T) {
+	doc, err := ParseDocument([]byte("a = {{\"{{\"}}n}} is {{n}}\nb = {{ \"{{\" }}}}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Render(doc, Values{"n": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Values{"a": {"{{n}} is 1"}, "b": {"{{}}"}}
+	if got := out.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %#v; want %#v", got, want)
+	}
+}
+
+func TestRender_dialects(t *testing.T) {
+	vars := Values{"v": {" x;y"}, "w": {"it's $HOME"}}
+	cases := []struct {
+		name   string
+		dec    *Reader
+		tmpl   string
+		want   string
+		values Values
+	}{
+		{"properties", &PropertiesDecoder, "a = {{v}}\nb = \"{{v}}\"\n", "a = \\ x;y\nb = \" x;y\"\n",
+			Values{"a": {" x;y"}, "b": {`" x;y"`}}},
+		{"dotenv", &Reader{DotEnv: true, Casing: CaseSensitive}, "A={{v}}\nB=\"{{w}}\"\nC={{w}}\n",
+			"A=' x;y'\nB=\"it's \\$HOME\"\nC=\"it's \\$HOME\"\n",
+			Values{"A": {" x;y"}, "B": {"it's $HOME"}, "C": {"it's $HOME"}}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			out, err := Render(testDocument(t, c.dec, c.tmpl), vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testDocumentSource(t, out, c.want)
+			if got := out.Values(); !reflect.DeepEqual(got, c.values) {
+				t.Errorf("Values() = %v; want %v", got, c.values)
+			}
+		})
+	}
+}
+
+func TestRender_directiveComment(t *testing.T) {
+	doc, err := ParseDocument([]byte("[a] ; @if env == prod ; primary\nk = 1\n" +
+		"[b] ; @if env == \"x;y\" # quoted\nk = 2\n" +
+		"[c] ; @range n in names # repeated\nk = {{n}}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Render(doc, Values{"env": {"prod"}, "names": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Values{"a.k": {"1"}, "c.k": {"1"}}
+	if got := out.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v; want %v", got, want)
+	}
+}
+
+func TestRender_errors(t *testing.T) {
+	cases := []struct {
+		name, tmpl string
+		line, col  int
+		err        error
+	}{
+		{"undefined", "a = 1\nb = {{missing}}\n", 2, 5, ErrUndefinedVar},
+		{"unclosed", "a = {{x\n", 1, 5, UnclosedError('{')},
+		{"bad escape", "a = {{\"x}}\n", 1, 5, ErrBadDirective},
+		{"misplaced directive", "[s]\n; @if x\n", 2, 1, ErrBadDirective},
+		{"preamble directive", "; @range x in y\n", 1, 1, ErrBadDirective},
+		{"bad range", "[s] ; @range x y\n", 1, 5, ErrBadDirective},
+		{"bad condition", "[s] ; @if x ==\n", 1, 5, ErrBadDirective},
+		{"bad string", "[s] ; @if x == \"y\n", 1, 5, ErrBadDirective},
+		{"empty section", "[{{x}}]\n", 1, 1, ErrInvalidKey},
+		{"invalid key", "[s]\n{{y}}_k = 1\n", 2, 1, ErrInvalidKey},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			doc, err := ParseDocument([]byte(c.tmpl))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Render(doc, Values{"x": {""}, "y": {"a b"}})
+			var terr *TemplateError
+			if !errors.As(err, &terr) || terr.Line != c.line || terr.Col != c.col || !errors.Is(err, c.err) {
+				t.Errorf("Render error = %v; want *TemplateError at %d:%d wrapping %v", err, c.line, c.col, c.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package ini
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"unicode"
+)
+
+// TokenKind is the kind of a Token read by a Scanner.
+type TokenKind int
+
+const (
+	// SectionStart is a section heading. Its Text is the key prefix the section produces, without
+	// a trailing separator (e.g., "a.b" for the heading `[a "b"]`).
+	SectionStart TokenKind = iota + 1
+	// Key is a key. Its Text is the full key, including its section prefix. If a Key token is not
+	// followed by a Value token, the key has no value and is recorded with the Reader's True
+	// value.
+	Key
+	// Value is the value of the preceding Key. Its Text is the decoded value.
+	Value
+	// Comment is a comment. Its Text is the comment following the ; or #, without a trailing
+	// newline.
+	Comment
+	// Blank is a line containing only whitespace. Its Text is empty.
+	Blank
+)
+
+var tokenKindNames = [...]string{
+	SectionStart: "SectionStart",
+	Key:          "Key",
+	Value:        "Value",
+	Comment:      "Comment",
+	Blank:        "Blank",
+}
+
+func (k TokenKind) String() string {
+	if k > 0 && int(k) < len(tokenKindNames) {
+		return tokenKindNames[k]
+	}
+	return "TokenKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Token is a single element of INI input read by a Scanner.
+type Token struct {
+	Kind TokenKind
+	// Text is the decoded text of the token. Its meaning depends on Kind.
+	Text string
+	// Raw is the input the token was read from. For values, this includes quotes and escape
+	// sequences; for comments, the leading ; or #. Raw does not include the newline ending a line.
+	Raw string
+	// Line and Col are the line and column (in runes) of the start of Raw, starting from 1.
+	Line, Col int
+	// Offset is the byte offset of the start of Raw in the input.
+	Offset int64
+}
+
+// Scanner reads INI input one token at a time. Unlike Reader.Read, which conveys only keys and
+// values to a Recorder, a Scanner also yields section headings, comments, and blank lines along
+// with their raw text and positions. This makes it suitable for tooling that needs to preserve or
+// inspect the structure of an INI file.
+//
+// A Scanner reads input as the Reader that created it would, including key casing and separators.
+type Scanner struct {
+	dec  decoder
+	next nextfunc
+	tok  Token
+	err  error
+}
+
+// NewScanner returns a Scanner that reads tokens from r as DefaultDecoder would.
+func NewScanner(r io.Reader) *Scanner {
+	return DefaultDecoder.NewScanner(r)
+}
+
+// NewScanner returns a Scanner that reads tokens from r as the receiver would. As with Read, the
+// receiver must not be modified while the Scanner is in use.
+func (d *Reader) NewScanner(r io.Reader) *Scanner {
+	s := &Scanner{next: (*decoder).start}
+	s.dec.reset(nil, d, nil, r)
+	s.dec.scan = true
+	return s
+}
+
+// Next advances the Scanner to the next token, which is then available from Token. It returns false
+// when there are no more tokens, either because input ended or an error occurred. Err returns the
+// error, if any.
+func (s *Scanner) Next() bool {
+	for len(s.dec.tokens) == 0 {
+		if s.next == nil || s.err != nil {
+			return false
+		}
+		s.dec.tokens = s.dec.tokens[:0]
+		s.next, s.err = s.next(&s.dec)
+	}
+
+	s.tok = s.dec.tokens[0]
+	s.dec.tokens = s.dec.tokens[1:]
+	return true
+}
+
+// Token returns the most recent token read by Next.
+func (s *Scanner) Token() Token {
+	return s.tok
+}
+
+// Err returns the first error encountered by the Scanner. As with Read, if input ends before
+// parsing is finished, Err returns io.ErrUnexpectedEOF or a *SyntaxError.
+func (s *Scanner) Err() error {
+	if s.err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return s.err
+}
+
+// mark is a position in the input.
+type mark struct {
+	off       int64
+	line, col int
+}
+
+// mark returns the position of the current rune.
+func (d *decoder) mark() mark {
+	if d.current == rNewline && d.err == nil {
+		// The newline belongs to the line it ends
+		return mark{off: d.offset, line: d.line - 1, col: d.nlCol}
+	}
+	return mark{off: d.offset, line: d.line, col: d.col}
+}
+
+// token queues a token of kind and text, read from the input from start up to end, if the decoder
+// is scanning.
+func (d *decoder) token(kind TokenKind, text string, start mark, end int64) {
+	if !d.scan {
+		return
+	}
+
+	d.tokens = append(d.tokens, Token{
+		Kind:   kind,
+		Text:   text,
+		Raw:    string(d.raw[start.off-d.rawOff : end-d.rawOff]),
+		Line:   start.line,
+		Col:    start.col,
+		Offset: start.off,
+	})
+	d.lastEnd = end
+}
+
+// trimmedEnd returns end moved back past any whitespace in the raw input between start and end.
+func (d *decoder) trimmedEnd(start mark, end int64) int64 {
+	if !d.scan {
+		return end
+	}
+	raw := bytes.TrimRightFunc(d.raw[start.off-d.rawOff:end-d.rawOff], unicode.IsSpace)
+	return start.off + int64(len(raw))
+}
+
+// trimRaw discards raw input that can no longer be part of a token. This is all input before the
+// current rune, unless the current rune is a newline, in which case the line it ends is kept in
+// case it is blank.
+func (d *decoder) trimRaw() {
+	if !d.scan {
+		return
+	}
+
+	base := d.offset
+	if d.current == rNewline && d.prevLineStart < base {
+		base = d.prevLineStart
+	}
+	if base <= d.rawOff {
+		return
+	}
+	n := copy(d.raw, d.raw[base-d.rawOff:])
+	d.raw = d.raw[:n]
+	d.rawOff = base
+}
+
+// readBlank skips whitespace, queuing a Blank token for each line that contains only whitespace.
+func (d *decoder) readBlank() (nextfunc, error) {
+	for unicode.IsSpace(d.current) {
+		if d.current == rNewline && d.lastEnd <= d.prevLineStart {
+			d.token(Blank, "", mark{off: d.prevLineStart, line: d.line - 1, col: 1}, d.offset)
+		}
+
+		if err := d.skip(); err == io.EOF {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return (*decoder).readElem, nil
+}
@@ -0,0 +1,95 @@
+package ini
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func scanAll(t *testing.T, dec *Reader, r io.Reader) ([]Token, error) {
+	t.Helper()
+	s := dec.NewScanner(r)
+	var toks []Token
+	for s.Next() {
+		toks = append(toks, s.Token())
+	}
+	return toks, s.Err()
+}
+
+func TestScanner(t *testing.T) {
+	src := "; header\n" +
+		"\n" +
+		"[a \"B\"] ; sect\n" +
+		"k = v  \n" +
+		"  flag\n" +
+		"  \t\n" +
+		"s = \"x\\ty\" # c\n" +
+		"é = `raw`\n" +
+		"e =\n" +
+		"last"
+
+	want := []Token{
+		{Kind: Comment, Text: " header", Raw: "; header", Line: 1, Col: 1, Offset: 0},
+		{Kind: Blank, Text: "", Raw: "", Line: 2, Col: 1, Offset: 9},
+		{Kind: SectionStart, Text: "a.B", Raw: `[a "B"]`, Line: 3, Col: 1, Offset: 10},
+		{Kind: Comment, Text: " sect", Raw: "; sect", Line: 3, Col: 9, Offset: 18},
+		{Kind: Key, Text: "a.B.k", Raw: "k", Line: 4, Col: 1, Offset: 25},
+		{Kind: Value, Text: "v", Raw: "v", Line: 4, Col: 5, Offset: 29},
+		{Kind: Key, Text: "a.B.flag", Raw: "flag", Line: 5, Col: 3, Offset: 35},
+		{Kind: Blank, Text: "", Raw: "  \t", Line: 6, Col: 1, Offset: 40},
+		{Kind: Key, Text: "a.B.s", Raw: "s", Line: 7, Col: 1, Offset: 44},
+		{Kind: Value, Text: "x\ty", Raw: `"x\ty"`, Line: 7, Col: 5, Offset: 48},
+		{Kind: Comment, Text: " c", Raw: "# c", Line: 7, Col: 12, Offset: 55},
+		{Kind: Key, Text: "a.B.é", Raw: "é", Line: 8, Col: 1, Offset: 59},
+		{Kind: Value, Text: "raw", Raw: "`raw`", Line: 8, Col: 5, Offset: 64},
+		{Kind: Key, Text: "a.B.e", Raw: "e", Line: 9, Col: 1, Offset: 70},
+		{Kind: Value, Text: "", Raw: "", Line: 9, Col: 4, Offset: 73},
+		{Kind: Key, Text: "a.B.last", Raw: "last", Line: 10, Col: 1, Offset: 74},
+	}
+
+	for desc, fn := range succReaders {
+		got, err := scanAll(t, &DefaultDecoder, fn(src))
+		if err != nil {
+			t.Errorf("%s: Err() = %v", desc, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: tokens =\n%v\nwant\n%v", desc, got, want)
+		}
+	}
+}
+
+func TestScanner_heredoc(t *testing.T) {
+	dec := Reader{Heredoc: true, ArrayKeys: true}
+	got, err := scanAll(t, &dec, strings.NewReader("k[] = <<EOF\na\nEOF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Token{
+		{Kind: Key, Text: "k", Raw: "k[]", Line: 1, Col: 1, Offset: 0},
+		{Kind: Value, Text: "a", Raw: "<<EOF\na\nEOF", Line: 1, Col: 7, Offset: 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens = %v; want %v", got, want)
+	}
+}
+
+func TestScanner_error(t *testing.T) {
+	s := NewScanner(iotest.OneByteReader(strings.NewReader("a = 1\nb = \"open")))
+	var kinds []TokenKind
+	for s.Next() {
+		kinds = append(kinds, s.Token().Kind)
+	}
+
+	if want := []TokenKind{Key, Value, Key}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("kinds = %v; want %v", kinds, want)
+	}
+	if _, ok := s.Err().(*SyntaxError); !ok {
+		t.Errorf("Err() = %v; want *SyntaxError", s.Err())
+	}
+	if s.Next() {
+		t.Error("Next() = true after error")
+	}
+}
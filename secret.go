@@ -0,0 +1,315 @@
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SecretPrefix marks a value as secret, wherever it is written (e.g., "password = secret:hunter2").
+// A Reader's Resolver also resolves references with this prefix, such as "secret:env:DB_PASS". Use
+// Reveal to get a secret without its prefix.
+const SecretPrefix = "secret:"
+
+// Redacted replaces secret values in formatted output.
+const Redacted = "[redacted]"
+
+// defaultSecretKeys are the patterns of secret keys used by a Redactor with nil Keys.
+var defaultSecretKeys = []string{
+	"*password*",
+	"*passwd*",
+	"*secret*",
+	"*token*",
+	"*apikey*",
+	"*api_key*",
+	"*private_key*",
+	"*credential*",
+}
+
+// Redactor determines which values are secret so that they can be kept out of output. A value is
+// secret if it has SecretPrefix or if its key matches one of the Redactor's Keys. A nil *Redactor
+// is the same as a zero Redactor, which uses the default patterns.
+//
+// The package-level IsSecret and Redact, Values.String and Values.GoString (and so fmt's %v and
+// %#v of Values, Frozen, and Tracked), and the Value of a *RecorderError redact secret values.
+// Writers of INI and other formats, such as Document.WriteTo, WriteDotEnv, WriteProperties,
+// WriteTOML, and ToJSON, write values as they are, since their output is meant to be read back; to
+// write redacted output, write the Values returned by Redactor.Values.
+//
+// A Redactor must not be modified while it is in use.
+type Redactor struct {
+	// Keys are the patterns of keys whose values are secret, regardless of SecretPrefix. Patterns
+	// use the syntax of path.Match and are matched against keys in lowercase, so they should be
+	// lowercase themselves. If Keys is nil, a default set of patterns matches keys containing
+	// words such as "password", "secret", "token", and "api_key". If Keys is empty but not nil,
+	// no key is secret.
+	Keys []string
+}
+
+// IsSecretKey returns true if key matches one of the Redactor's Keys.
+func (r *Redactor) IsSecretKey(key string) bool {
+	patterns := defaultSecretKeys
+	if r != nil && r.Keys != nil {
+		patterns = r.Keys
+	}
+
+	key = strings.ToLower(key)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSecret returns true if value is secret, either because it has SecretPrefix or because key is a
+// secret key.
+func (r *Redactor) IsSecret(key, value string) bool {
+	return strings.HasPrefix(value, SecretPrefix) || r.IsSecretKey(key)
+}
+
+// Redact returns Redacted if value is secret and value otherwise.
+func (r *Redactor) Redact(key, value string) string {
+	if r.IsSecret(key, value) {
+		return Redacted
+	}
+	return value
+}
+
+// Values returns a copy of v with its secret values replaced by Redacted.
+func (r *Redactor) Values(v Values) Values {
+	dst := make(Values, len(v))
+	for k, vs := range v {
+		if vs == nil {
+			dst[k] = nil
+			continue
+		}
+		redacted := make([]string, len(vs))
+		for i, value := range vs {
+			redacted[i] = r.Redact(k, value)
+		}
+		dst[k] = redacted
+	}
+	return dst
+}
+
+// IsSecret returns true if value is secret to a Redactor with the default patterns.
+func IsSecret(key, value string) bool {
+	return (*Redactor)(nil).IsSecret(key, value)
+}
+
+// Redact returns Redacted if value is secret to a Redactor with the default patterns, and value
+// otherwise.
+func Redact(key, value string) string {
+	return (*Redactor)(nil).Redact(key, value)
+}
+
+// Reveal returns the first value for key without SecretPrefix. If key does not exist or has an
+// empty value slice, Reveal returns an empty string.
+func (v Values) Reveal(key string) string {
+	return strings.TrimPrefix(v.Get(key), SecretPrefix)
+}
+
+// Reveal returns the first value for key without SecretPrefix, as Values.Reveal does.
+func (f *Frozen) Reveal(key string) string {
+	return strings.TrimPrefix(f.Get(key), SecretPrefix)
+}
+
+// Reveal returns the first value for key, falling back to the default section as Get does, without
+// SecretPrefix.
+func (d *Defaults) Reveal(key string) string {
+	return strings.TrimPrefix(d.Get(key), SecretPrefix)
+}
+
+// String returns the receiver as INI, one "key = value" line per value in sorted key order, with
+// values that are secret to a Redactor with the default patterns redacted. Keys without values are
+// written without a value. Because Values is a Stringer, this is also how fmt prints it for %v.
+func (v Values) String() string {
+	var b strings.Builder
+	for _, k := range v.sortedKeys() {
+		vs := v[k]
+		if len(vs) == 0 {
+			b.WriteString(k)
+			b.WriteByte(rNewline)
+		}
+		for _, value := range vs {
+			b.WriteString(k)
+			b.WriteString(" = ")
+			if IsSecret(k, value) {
+				b.WriteString(Redacted)
+			} else {
+				b.WriteString(quoteValue(value))
+			}
+			b.WriteByte(rNewline)
+		}
+	}
+	return b.String()
+}
+
+// GoString returns the receiver as a Go map literal in sorted key order, with secret values
+// redacted as String does.
+func (v Values) GoString() string {
+	return goString("ini.Values", v)
+}
+
+// goString returns v as a Go map literal of the type typ, with secret values redacted.
+func goString(typ string, v Values) string {
+	if v == nil {
+		return typ + "(nil)"
+	}
+
+	var b strings.Builder
+	b.WriteString(typ)
+	b.WriteByte('{')
+	for i, k := range v.sortedKeys() {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(strconv.Quote(k))
+		vs := v[k]
+		if vs == nil {
+			b.WriteString(":[]string(nil)")
+			continue
+		}
+
+		b.WriteString(":[]string{")
+		for j, value := range vs {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(strconv.Quote(Redact(k, value)))
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// String returns the snapshot's values as INI, as Values.String does.
+func (f *Frozen) String() string {
+	return f.Thaw().String()
+}
+
+// GoString returns the snapshot's values as a Go expression, as Values.GoString does.
+func (f *Frozen) GoString() string {
+	if f.sep != "" {
+		return goString("ini.Values", f.Thaw()) + fmt.Sprintf(".FreezeSep(%q)", f.sep)
+	}
+	return goString("ini.Values", f.Thaw()) + ".Freeze()"
+}
+
+// SecretResolver resolves references to secrets, such as "file:/run/secrets/db", when reading
+// input. A Reader with a Resolver passes it the references in its input and records resolved
+// secrets in place of them. See Reader.Resolver for which values are references.
+type SecretResolver interface {
+	// ResolveSecret returns the secret that ref, the value of key, refers to. If ref is not a
+	// reference the resolver handles, ResolveSecret returns false and no error.
+	ResolveSecret(key, ref string) (secret string, ok bool, err error)
+}
+
+// SecretResolvers is a SecretResolver that resolves references with the first of its resolvers
+// that handles them.
+type SecretResolvers []SecretResolver
+
+// ResolveSecret resolves ref with the first resolver that handles it.
+func (rs SecretResolvers) ResolveSecret(key, ref string) (string, bool, error) {
+	for _, r := range rs {
+		if secret, ok, err := r.ResolveSecret(key, ref); ok || err != nil {
+			return secret, ok, err
+		}
+	}
+	return "", false, nil
+}
+
+// FileResolver resolves references of the form "file:PATH" to the contents of the file at PATH,
+// less a trailing newline. Relative paths are relative to Root.
+type FileResolver struct {
+	// Root is the directory that secret files must be in. References to files outside of Root,
+	// including through symbolic links, are an error. If Root is empty, every file reference is an
+	// error, so that input cannot read arbitrary files; to allow any file, set Root to "/".
+	Root string
+}
+
+// ResolveSecret reads the file that ref refers to, if ref has the prefix "file:".
+func (r FileResolver) ResolveSecret(_, ref string) (string, bool, error) {
+	name, ok := strings.CutPrefix(ref, "file:")
+	if !ok {
+		return "", false, nil
+	} else if r.Root == "" {
+		return "", true, errors.New("ini: FileResolver has no Root")
+	}
+
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(r.Root, name)
+	}
+	root, err := filepath.EvalSymlinks(r.Root)
+	if err != nil {
+		return "", true, err
+	}
+	real, err := filepath.EvalSymlinks(name)
+	if err != nil {
+		return "", true, err
+	}
+	if rel, err := filepath.Rel(root, real); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", true, fmt.Errorf("ini: secret file %s is not in %s", name, r.Root)
+	}
+
+	b, err := os.ReadFile(real)
+	if err != nil {
+		return "", true, err
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	return strings.TrimSuffix(s, "\r"), true, nil
+}
+
+// EnvResolver resolves references of the form "env:NAME" to the value of the environment variable
+// NAME. If the variable is not set, it returns ErrSecretNotFound.
+type EnvResolver struct {
+	// LookupEnv looks up environment variables. If nil, os.LookupEnv is used.
+	LookupEnv func(name string) (string, bool)
+}
+
+// ResolveSecret returns the environment variable that ref refers to, if ref has the prefix "env:".
+func (r EnvResolver) ResolveSecret(_, ref string) (string, bool, error) {
+	name, ok := strings.CutPrefix(ref, "env:")
+	if !ok {
+		return "", false, nil
+	}
+
+	lookup := r.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	if secret, ok := lookup(name); ok {
+		return secret, true, nil
+	}
+	return "", true, fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, name)
+}
+
+// resolve resolves value with the decoder's Resolver, if any and if value is a reference. If the
+// decoder marks secrets, the secret is recorded with SecretPrefix unless key is a secret key.
+func (d *decoder) resolve(key, value string) (string, error) {
+	if d.resolver == nil {
+		return value, nil
+	}
+
+	secretKey := d.redactor.IsSecretKey(key)
+	ref, explicit := strings.CutPrefix(value, SecretPrefix)
+	if !explicit && !secretKey && !strings.HasPrefix(value, "enc:") {
+		return value, nil
+	}
+
+	secret, ok, err := d.resolver.ResolveSecret(key, ref)
+	if err != nil {
+		return "", &SecretError{Key: key, Err: err}
+	} else if !ok {
+		return value, nil
+	} else if d.markSecrets && !secretKey {
+		secret = SecretPrefix + secret
+	}
+	return secret, nil
+}
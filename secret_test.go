@@ -0,0 +1,206 @@
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsSecret(t *testing.T) {
+	cases := []struct {
+		key, value string
+		want       bool
+	}{
+		{"db.password", "hunter2", true},
+		{"db.Password", "hunter2", true},
+		{"github.api_token", "x", true},
+		{"db.host", "localhost", false},
+		{"db.dsn", "secret:postgres://u:p@h/db", true},
+		{"db.dsn", "postgres://h/db", false},
+	}
+	for _, c := range cases {
+		if got := IsSecret(c.key, c.value); got != c.want {
+			t.Errorf("IsSecret(%q, %q) = %t; want %t", c.key, c.value, got, c.want)
+		}
+		want := c.value
+		if c.want {
+			want = Redacted
+		}
+		if got := Redact(c.key, c.value); got != want {
+			t.Errorf("Redact(%q, %q) = %q; want %q", c.key, c.value, got, want)
+		}
+	}
+}
+
+func TestValues_String(t *testing.T) {
+	v := Values{
+		"db.host":     {"localhost"},
+		"db.password": {"hunter2"},
+		"db.dsn":      {"secret:postgres://u:p@h/db"},
+		"flag":        nil,
+		"list":        {"a b", " padded "},
+	}
+
+	want := "db.dsn = [redacted]\n" +
+		"db.host = localhost\n" +
+		"db.password = [redacted]\n" +
+		"flag\n" +
+		"list = a b\n" +
+		"list = \" padded \"\n"
+	if got := v.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+	if got := fmt.Sprint(v); got != want {
+		t.Errorf("Sprint() =\n%s\nwant\n%s", got, want)
+	}
+
+	want = `ini.Values{"db.dsn":[]string{"[redacted]"}, "db.host":[]string{"localhost"}, ` +
+		`"db.password":[]string{"[redacted]"}, "flag":[]string(nil), "list":[]string{"a b", " padded "}}`
+	if got := fmt.Sprintf("%#v", v); got != want {
+		t.Errorf("GoString() = %s; want %s", got, want)
+	}
+	if got, want := fmt.Sprintf("%#v", Values(nil)), "ini.Values(nil)"; got != want {
+		t.Errorf("GoString() = %s; want %s", got, want)
+	}
+
+	f := v.Freeze()
+	for _, s := range []string{fmt.Sprint(f), fmt.Sprintf("%#v", f), fmt.Sprintf("%+v", Tracked{Values: v})} {
+		if strings.Contains(s, "hunter2") || strings.Contains(s, "postgres") {
+			t.Errorf("formatted output contains secret: %s", s)
+		}
+	}
+
+	if got := v.Reveal("db.dsn"); got != "postgres://u:p@h/db" {
+		t.Errorf("Reveal() = %q; want %q", got, "postgres://u:p@h/db")
+	}
+}
+
+func TestReader_Resolver(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "db"), []byte("hunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "outside")
+	if err := os.WriteFile(outside, []byte("nope"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	env := EnvResolver{LookupEnv: func(name string) (string, bool) {
+		if name == "API_KEY" {
+			return "abc123", true
+		}
+		return "", false
+	}}
+	dec := Reader{
+		Separator: ".",
+		Casing:    CaseSensitive,
+		Resolver:  SecretResolvers{FileResolver{Root: dir}, env},
+	}
+
+	src := "[db]\npassword = file:db\nabs = secret:file:" + filepath.Join(dir, "db") + "\nnote = file:db\n" +
+		"[api]\nkey = secret:env:API_KEY\nurl = https://example.com\nliteral = secret:not a reference\n"
+	testReadINIMatching(t, &dec, src, Values{
+		"db.password": {"hunter2"},
+		"db.abs":      {"hunter2"},
+		"db.note":     {"file:db"},
+		"api.key":     {"abc123"},
+		"api.url":     {"https://example.com"},
+		"api.literal": {"secret:not a reference"},
+	})
+
+	for _, src := range []string{
+		"k = secret:env:MISSING",
+		"k = secret:file:missing",
+		"k = secret:file:../outside",
+		"k = secret:file:" + outside,
+		"k = secret:file:link",
+		"password = env:MISSING",
+	} {
+		err := dec.Read(strings.NewReader(src), Values{})
+		var se *SecretError
+		if key, _, _ := strings.Cut(src, " "); !errors.As(err, &se) || se.Key != key {
+			t.Errorf("Read(%q) = %v; want *SecretError for %s", src, err, key)
+		}
+	}
+
+	err := dec.Read(strings.NewReader("k = secret:env:MISSING"), Values{})
+	if !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Read() = %v; want ErrSecretNotFound", err)
+	}
+
+	// Without a Root, no file can be read
+	dec.Resolver = FileResolver{}
+	if err := dec.Read(strings.NewReader("k = secret:file:"+outside), Values{}); err == nil {
+		t.Error("Read() with an empty Root = nil; want error")
+	}
+
+	// A Redactor decides which keys are secret
+	dec.Resolver, dec.Redactor = env, &Redactor{Keys: []string{"*.key"}}
+	testReadINIMatching(t, &dec, "[api]\nkey = env:API_KEY\npassword = env:API_KEY\n", Values{
+		"api.key":      {"abc123"},
+		"api.password": {"env:API_KEY"},
+	})
+}
+
+func TestRedactor(t *testing.T) {
+	r := &Redactor{Keys: []string{"*.pin"}}
+	if !r.IsSecret("card.PIN", "1234") || r.IsSecret("db.password", "x") || !r.IsSecret("k", "secret:x") {
+		t.Error("IsSecret does not use the Redactor's Keys")
+	}
+	if none := (&Redactor{Keys: []string{}}); none.IsSecretKey("password") {
+		t.Error("IsSecretKey(password) = true with empty Keys; want false")
+	}
+
+	v := Values{"card.pin": {"1234", "5678"}, "card.name": {"x"}, "flag": nil}
+	want := Values{"card.pin": {Redacted, Redacted}, "card.name": {"x"}, "flag": nil}
+	if got := r.Values(v); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %#v; want %#v", got, want)
+	}
+	if v.Get("card.pin") != "1234" {
+		t.Error("Values() modified its argument")
+	}
+}
+
+func TestReveal(t *testing.T) {
+	v := Values{"s.dsn": {"secret:postgres://h/db"}, "default.token": {"secret:abc"}}
+	if got := v.Freeze().Reveal("s.dsn"); got != "postgres://h/db" {
+		t.Errorf("Frozen.Reveal() = %q; want %q", got, "postgres://h/db")
+	}
+	if got := v.WithDefaults("default").Reveal("s.token"); got != "abc" {
+		t.Errorf("Defaults.Reveal() = %q; want %q", got, "abc")
+	}
+}
+
+func TestRecorderError_redacted(t *testing.T) {
+	var re *RecorderError
+	err := DefaultDecoder.Read(strings.NewReader("password = hunter2"), panicRecorder{"boom"})
+	if !errors.As(err, &re) || re.Value != Redacted {
+		t.Errorf("Read() = %#v; want *RecorderError with a redacted Value", err)
+	}
+}
+
+func TestRedactor_writers(t *testing.T) {
+	v := Values{"db.password": {"hunter2"}}
+
+	// Writers write values as they are, and redact only the Values returned by Redactor.Values
+	var buf strings.Builder
+	if err := WriteTOML(&buf, testTree(t, v, "")); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("WriteTOML() = %q; want the value as it is", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteTOML(&buf, testTree(t, (*Redactor)(nil).Values(v), "")); err != nil {
+		t.Fatal(err)
+	} else if got := buf.String(); strings.Contains(got, "hunter2") || !strings.Contains(got, Redacted) {
+		t.Errorf("WriteTOML(redacted) = %q; want the value redacted", got)
+	}
+}
@@ -0,0 +1,730 @@
+package ini
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// WriteTOML writes tree as TOML. Maps in tree are written as tables, strings as basic strings, and
+// slices as arrays. Booleans and numbers are written as TOML booleans and numbers. Keys that are
+// not valid bare keys are quoted. WriteTOML returns an error if tree contains any other type,
+// including slices of maps.
+//
+// Only the subset of TOML that corresponds to INI is supported. To write Values as TOML, convert
+// them with ToTree.
+func WriteTOML(w io.Writer, tree map[string]interface{}) error {
+	bw := bufio.NewWriter(w)
+	tw := tomlWriter{w: bw}
+	if err := tw.table(nil, tree); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// tomlWriter writes TOML tables.
+type tomlWriter struct {
+	w       *bufio.Writer
+	written bool // written is true once anything has been written
+}
+
+// table writes the table at path, followed by its subtables.
+func (tw *tomlWriter) table(path []string, tree map[string]interface{}) error {
+	keys := sortedTreeKeys(tree)
+
+	var tables []string
+	hasValues := false
+	for _, k := range keys {
+		if _, ok := tree[k].(map[string]interface{}); ok {
+			tables = append(tables, k)
+		} else {
+			hasValues = true
+		}
+	}
+
+	// Write a heading for tables with values or no subtables (so that empty tables are kept)
+	if len(path) > 0 && (hasValues || len(tables) == 0) {
+		if tw.written {
+			tw.w.WriteByte('\n')
+		}
+		tw.w.WriteByte('[')
+		for i, name := range path {
+			if i > 0 {
+				tw.w.WriteByte('.')
+			}
+			tw.w.WriteString(tomlKey(name))
+		}
+		tw.w.WriteString("]\n")
+		tw.written = true
+	}
+
+	for _, k := range keys {
+		value := tree[k]
+		if _, ok := value.(map[string]interface{}); ok || value == nil {
+			continue
+		}
+		s, err := tomlValue(value)
+		if err != nil {
+			return fmt.Errorf("ini: writing TOML key %q: %w", strings.Join(append(path, k), "."), err)
+		}
+		tw.w.WriteString(tomlKey(k))
+		tw.w.WriteString(" = ")
+		tw.w.WriteString(s)
+		tw.w.WriteByte('\n')
+		tw.written = true
+	}
+
+	for _, k := range tables {
+		if err := tw.table(append(path[:len(path):len(path)], k), tree[k].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tomlKey returns key as a bare key if possible, or a quoted key.
+func tomlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return tomlString(key)
+		}
+	}
+	return key
+}
+
+// tomlValue returns the TOML representation of a value.
+func tomlValue(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return tomlString(value), nil
+	case []string:
+		elems := make([]string, len(value))
+		for i, s := range value {
+			elems[i] = tomlString(s)
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+	case []interface{}:
+		elems := make([]string, len(value))
+		for i, e := range value {
+			if _, ok := e.([]interface{}); ok {
+				return "", errors.New("nested arrays are not supported")
+			}
+			s, err := tomlValue(e)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = s
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+	case bool, int, int64, uint64:
+		return fmt.Sprint(value), nil
+	case float64:
+		s := formatFloat(value)
+		if !strings.ContainsAny(s, ".eEn") {
+			s += ".0"
+		}
+		return s, nil
+	default:
+		return "", fmt.Errorf("cannot write %T as TOML", value)
+	}
+}
+
+// tomlString returns s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// ReadTOML reads TOML from r and returns it as a tree of nested maps, as accepted by FromTree.
+// Tables are read as maps, strings as strings, integers as int64, floats as float64, booleans as
+// bools, and arrays as []interface{}. Dates and times are read as strings.
+//
+// Only the subset of TOML that corresponds to INI is supported: inline tables and arrays of tables
+// are not. Syntax errors are returned as a *SyntaxError.
+func ReadTOML(r io.Reader) (map[string]interface{}, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(src) {
+		return nil, errors.New("ini: TOML input is not valid UTF-8")
+	}
+
+	p := tomlParser{src: string(src), line: 1, root: make(map[string]interface{})}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.root, nil
+}
+
+// tomlParser parses TOML input.
+type tomlParser struct {
+	src       string
+	pos       int
+	line      int // line is the line of src[pos]
+	lineStart int // lineStart is the offset of the start of the line
+	root      map[string]interface{}
+	table     map[string]interface{}
+	defined   map[string]bool // defined is the set of table headers seen
+}
+
+var (
+	errTOMLUnsupported = errors.New("ini: unsupported TOML")
+	errTOMLDuplicate   = errors.New("ini: duplicate TOML key")
+)
+
+// err returns a *SyntaxError at the current position.
+func (p *tomlParser) err(err error, desc string) error {
+	col := utf8.RuneCountInString(p.src[p.lineStart:p.pos]) + 1
+	return &SyntaxError{Line: p.line, Col: col, Err: err, Desc: desc}
+}
+
+// peek returns the current byte, or 0 at the end of input.
+func (p *tomlParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+// skip consumes n bytes, none of which may be a newline.
+func (p *tomlParser) skip(n int) {
+	p.pos += n
+}
+
+// newline consumes a newline.
+func (p *tomlParser) newline() {
+	p.pos++
+	p.line++
+	p.lineStart = p.pos
+}
+
+// space skips spaces and tabs.
+func (p *tomlParser) space() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.skip(1)
+	}
+}
+
+// blank skips whitespace, newlines, and comments.
+func (p *tomlParser) blank() {
+	for {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.skip(1)
+		case '\n':
+			p.newline()
+		case '#':
+			p.comment()
+		default:
+			return
+		}
+	}
+}
+
+// comment skips a comment, up to the newline ending it.
+func (p *tomlParser) comment() {
+	if i := strings.IndexByte(p.src[p.pos:], '\n'); i != -1 {
+		p.skip(i)
+	} else {
+		p.pos = len(p.src)
+	}
+}
+
+// endLine consumes the end of a line, including any comment.
+func (p *tomlParser) endLine() error {
+	p.space()
+	switch p.peek() {
+	case '#':
+		p.comment()
+	case '\r':
+		p.skip(1)
+	}
+	switch p.peek() {
+	case 0:
+		return nil
+	case '\n':
+		p.newline()
+		return nil
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		return p.err(BadCharError(r), "expected end of line")
+	}
+}
+
+func (p *tomlParser) parse() error {
+	p.table = p.root
+	p.defined = make(map[string]bool)
+	for {
+		p.blank()
+		switch p.peek() {
+		case 0:
+			return nil
+		case '[':
+			if err := p.header(); err != nil {
+				return err
+			}
+		default:
+			if err := p.keyValue(); err != nil {
+				return err
+			}
+		}
+		if err := p.endLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// header parses a table header.
+func (p *tomlParser) header() error {
+	p.skip(1)
+	if p.peek() == '[' {
+		return p.err(errTOMLUnsupported, "arrays of tables are not supported")
+	}
+
+	p.space()
+	path, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.space()
+	if p.peek() != ']' {
+		return p.err(UnclosedError('['), "expected ] after table name")
+	}
+	p.skip(1)
+
+	table := p.root
+	for _, name := range path {
+		switch sub := table[name].(type) {
+		case map[string]interface{}:
+			table = sub
+		case nil:
+			next := make(map[string]interface{})
+			table[name] = next
+			table = next
+		default:
+			return p.err(errTOMLDuplicate, fmt.Sprintf("%q is already defined as a value", name))
+		}
+	}
+	name := strings.Join(path, "\x00")
+	if p.defined[name] {
+		return p.err(errTOMLDuplicate, "table is defined more than once")
+	}
+	p.defined[name] = true
+	p.table = table
+	return nil
+}
+
+// keyValue parses a key/value pair.
+func (p *tomlParser) keyValue() error {
+	path, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.space()
+	if p.peek() != '=' {
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		return p.err(BadCharError(r), "expected = after key")
+	}
+	p.skip(1)
+	p.space()
+
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+
+	table := p.table
+	for _, name := range path[:len(path)-1] {
+		switch sub := table[name].(type) {
+		case map[string]interface{}:
+			table = sub
+		case nil:
+			next := make(map[string]interface{})
+			table[name] = next
+			table = next
+		default:
+			return p.err(errTOMLDuplicate, fmt.Sprintf("%q is already defined as a value", name))
+		}
+	}
+
+	name := path[len(path)-1]
+	if _, ok := table[name]; ok {
+		return p.err(errTOMLDuplicate, fmt.Sprintf("key %q is defined more than once", name))
+	}
+	table[name] = value
+	return nil
+}
+
+// key parses a possibly dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	var path []string
+	for {
+		var name string
+		switch c := p.peek(); {
+		case c == '"':
+			s, err := p.basicString()
+			if err != nil {
+				return nil, err
+			}
+			name = s
+		case c == '\'':
+			s, err := p.literalString()
+			if err != nil {
+				return nil, err
+			}
+			name = s
+		default:
+			start := p.pos
+			for c := p.peek(); c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'; c = p.peek() {
+				p.skip(1)
+			}
+			if p.pos == start {
+				return nil, p.err(ErrEmptyKey, "expected a key")
+			}
+			name = p.src[start:p.pos]
+		}
+		path = append(path, name)
+
+		p.space()
+		if p.peek() != '.' {
+			return path, nil
+		}
+		p.skip(1)
+		p.space()
+	}
+}
+
+// value parses a value.
+func (p *tomlParser) value() (interface{}, error) {
+	switch c := p.peek(); c {
+	case '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			return p.multilineString(`"""`, true)
+		}
+		return p.basicString()
+	case '\'':
+		if strings.HasPrefix(p.src[p.pos:], `'''`) {
+			return p.multilineString(`'''`, false)
+		}
+		return p.literalString()
+	case '[':
+		return p.array()
+	case '{':
+		return nil, p.err(errTOMLUnsupported, "inline tables are not supported")
+	case 0, '\n', '\r', '#':
+		return nil, p.err(io.ErrUnexpectedEOF, "expected a value")
+	}
+
+	// Booleans, numbers, and dates
+	start := p.pos
+	for c := p.peek(); c != 0 && !strings.ContainsRune(" \t\r\n,]#", rune(c)); c = p.peek() {
+		p.skip(1)
+	}
+	// Allow a space between a date and time
+	if p.peek() == ' ' && p.pos-start == 10 && p.src[start+4] == '-' && p.pos+1 < len(p.src) &&
+		p.src[p.pos+1] >= '0' && p.src[p.pos+1] <= '9' {
+		p.skip(1)
+		for c := p.peek(); c != 0 && !strings.ContainsRune(" \t\r\n,]#", rune(c)); c = p.peek() {
+			p.skip(1)
+		}
+	}
+	raw := p.src[start:p.pos]
+
+	switch raw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf", "-inf", "nan", "+nan", "-nan":
+		f, _ := strconv.ParseFloat(strings.TrimPrefix(raw, "+"), 64)
+		return f, nil
+	}
+	if i, ok := tomlInteger(raw); ok {
+		return i, nil
+	}
+	if f, ok := tomlFloat(raw); ok {
+		return f, nil
+	}
+	if len(raw) >= 8 && (raw[2] == ':' || len(raw) >= 10 && raw[4] == '-' && raw[7] == '-') {
+		return raw, nil
+	}
+	p.pos = start
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return nil, p.err(BadCharError(r), "expected a value")
+}
+
+// tomlInteger parses raw as a TOML integer. Decimal integers may have a sign but no leading zeros,
+// and hexadecimal, octal, and binary integers have a lowercase prefix (0x, 0o, or 0b) and no sign.
+// Underscores may appear only between digits.
+func tomlInteger(raw string) (int64, bool) {
+	base, digits, isDigit := 10, raw, isDecDigit
+	if len(raw) > 2 && raw[0] == '0' {
+		switch raw[1] {
+		case 'x':
+			base, isDigit = 16, isHexDigit
+		case 'o':
+			base, isDigit = 8, func(c byte) bool { return c >= '0' && c <= '7' }
+		case 'b':
+			base, isDigit = 2, func(c byte) bool { return c == '0' || c == '1' }
+		}
+		if base != 10 {
+			digits = raw[2:]
+		}
+	}
+	if base == 10 && !tomlDecimal(raw) || base != 10 && !tomlDigits(digits, isDigit) {
+		return 0, false
+	}
+
+	digits = strings.ReplaceAll(digits, "_", "")
+	if base != 10 {
+		// Non-decimal integers are unsigned, but must fit in an int64
+		u, err := strconv.ParseUint(digits, base, 63)
+		return int64(u), err == nil
+	}
+	i, err := strconv.ParseInt(digits, 10, 64)
+	return i, err == nil
+}
+
+// tomlFloat parses raw as a TOML float: a decimal integer followed by a fractional part, an
+// exponent, or both. Special values (inf and nan) are handled by the caller.
+func tomlFloat(raw string) (float64, bool) {
+	mant, exp, hasExp := strings.Cut(raw, "e")
+	if !hasExp {
+		mant, exp, hasExp = strings.Cut(raw, "E")
+	}
+	whole, frac, hasFrac := strings.Cut(mant, ".")
+	if !hasFrac && !hasExp || !tomlDecimal(whole) || hasFrac && !tomlDigits(frac, isDecDigit) {
+		return 0, false
+	}
+	if hasExp {
+		if exp != "" && (exp[0] == '+' || exp[0] == '-') {
+			exp = exp[1:]
+		}
+		if !tomlDigits(exp, isDecDigit) {
+			return 0, false
+		}
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(raw, "_", ""), 64)
+	return f, err == nil
+}
+
+// tomlDecimal returns whether s is a TOML decimal integer: an optional sign followed by digits with
+// no leading zeros.
+func tomlDecimal(s string) bool {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	return tomlDigits(s, isDecDigit) && (s == "0" || s[0] != '0')
+}
+
+// tomlDigits returns whether s is one or more digits, as determined by isDigit, with underscores
+// allowed only between digits.
+func tomlDigits(s string, isDigit func(byte) bool) bool {
+	if s == "" || !isDigit(s[0]) || !isDigit(s[len(s)-1]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] == '_' && s[i-1] == '_' || s[i] != '_' && !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDecDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDecDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// array parses an array, which may span several lines.
+func (p *tomlParser) array() ([]interface{}, error) {
+	p.skip(1)
+	elems := []interface{}{}
+	for {
+		p.blank()
+		if p.peek() == ']' {
+			p.skip(1)
+			return elems, nil
+		}
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, v)
+
+		p.blank()
+		switch p.peek() {
+		case ',':
+			p.skip(1)
+		case ']':
+		default:
+			return nil, p.err(UnclosedError('['), "expected , or ] in array")
+		}
+	}
+}
+
+// basicString parses a string in double quotes.
+func (p *tomlParser) basicString() (string, error) {
+	p.skip(1)
+	var b strings.Builder
+	for {
+		switch c := p.peek(); c {
+		case 0, '\n':
+			return "", p.err(UnclosedError('"'), "unterminated string")
+		case '"':
+			p.skip(1)
+			return b.String(), nil
+		case '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.skip(1)
+		}
+	}
+}
+
+// escape parses an escape sequence in a basic string.
+func (p *tomlParser) escape(b *strings.Builder) error {
+	p.skip(1)
+	c := p.peek()
+	p.skip(1)
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			return p.err(io.ErrUnexpectedEOF, "unterminated unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.err(BadCharError(rune(p.src[p.pos])), "invalid unicode escape")
+		}
+		b.WriteRune(rune(code))
+		p.skip(n)
+	default:
+		p.pos--
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		return p.err(BadCharError(r), "invalid escape sequence")
+	}
+	return nil
+}
+
+// literalString parses a string in single quotes.
+func (p *tomlParser) literalString() (string, error) {
+	p.skip(1)
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end == -1 || p.src[p.pos+end] == '\n' {
+		return "", p.err(UnclosedError('\''), "unterminated string")
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.skip(end + 1)
+	return s, nil
+}
+
+// multilineString parses a multi-line string delimited by delim. A newline immediately following
+// the opening delimiter is removed. If basic is true, escapes are processed, and a backslash at the
+// end of a line removes the newline and any whitespace that follows it.
+func (p *tomlParser) multilineString(delim string, basic bool) (string, error) {
+	p.skip(len(delim))
+	if strings.HasPrefix(p.src[p.pos:], "\r\n") {
+		p.skip(1)
+	}
+	if p.peek() == '\n' {
+		p.newline()
+	}
+
+	var b strings.Builder
+	for {
+		if strings.HasPrefix(p.src[p.pos:], delim) {
+			// Up to two quotes may precede the closing delimiter
+			for i := 0; i < 2 && strings.HasPrefix(p.src[p.pos+1:], delim); i++ {
+				b.WriteByte(delim[0])
+				p.skip(1)
+			}
+			p.skip(len(delim))
+			return b.String(), nil
+		}
+
+		switch c := p.peek(); {
+		case c == 0:
+			return "", p.err(UnclosedError(rune(delim[0])), "unterminated multi-line string")
+		case c == '\n':
+			b.WriteByte(c)
+			p.newline()
+		case c == '\\' && basic:
+			rest := strings.TrimLeft(p.src[p.pos+1:], " \t\r")
+			if strings.HasPrefix(rest, "\n") {
+				// Line-ending backslash
+				p.pos = len(p.src) - len(rest)
+				for c := p.peek(); c == '\n' || c == ' ' || c == '\t' || c == '\r'; c = p.peek() {
+					if c == '\n' {
+						p.newline()
+					} else {
+						p.skip(1)
+					}
+				}
+				continue
+			}
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.skip(1)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteTOML(t *testing.T) {
+	v := Values{
+		"top":         {"x"},
+		"a":           {"leaf"},
+		"a.b":         {"1", "two"},
+		"a.c.d":       {"quote \" and \\ and\nnewline"},
+		"empty":       nil,
+		"s.t.u":       {"\x01"},
+		"my key.name": {"v"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTOML(&buf, testTree(t, v, "")); err != nil {
+		t.Fatal(err)
+	}
+	want := "empty = []\n" +
+		"top = \"x\"\n" +
+		"\n" +
+		"[a]\n" +
+		"\"\" = \"leaf\"\n" +
+		"b = [\"1\", \"two\"]\n" +
+		"\n" +
+		"[a.c]\n" +
+		"d = \"quote \\\" and \\\\ and\\nnewline\"\n" +
+		"\n" +
+		"[\"my key\"]\n" +
+		"name = \"v\"\n" +
+		"\n" +
+		"[s.t]\n" +
+		"u = \"\\u0001\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteTOML() =\n%s\nwant\n%s", got, want)
+	}
+
+	tree, err := ReadTOML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := FromTree(tree, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, v) {
+		t.Errorf("FromTree(ReadTOML()) = %v; want %v", back, v)
+	}
+
+	buf.Reset()
+	err = WriteTOML(&buf, map[string]interface{}{"t": map[string]interface{}{}, "f": 2.0, "i": 3, "b": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "b = true\nf = 2.0\ni = 3\n\n[t]\n"; got != want {
+		t.Errorf("WriteTOML() = %q; want %q", got, want)
+	}
+
+	if err := WriteTOML(&buf, map[string]interface{}{"k": []interface{}{map[string]interface{}{}}}); err == nil {
+		t.Error("WriteTOML(array of tables) = nil; want error")
+	}
+}
+
+func TestReadTOML(t *testing.T) {
+	const src = `# comment
+title = "TOML" # trailing
+literal = 'C:\path'
+int = 1_000
+hex = 0xff
+neg = -3
+float = 6.25e-1
+inf = -inf
+bool = false
+date = 1979-05-27
+datetime = 1979-05-27 07:32:00Z
+arr = [
+  "a", # comment
+  'b',
+]
+
+[server]
+host.name = "h"
+"quoted key" = """
+line one
+line \
+    two"""
+raw = '''
+it's raw\n'''
+
+[server.sub]
+x = "\u00e9\U0001F600"
+`
+	tree, err := ReadTOML(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"title":    "TOML",
+		"literal":  `C:\path`,
+		"int":      int64(1000),
+		"hex":      int64(255),
+		"neg":      int64(-3),
+		"float":    0.625,
+		"inf":      math.Inf(-1),
+		"bool":     false,
+		"date":     "1979-05-27",
+		"datetime": "1979-05-27 07:32:00Z",
+		"arr":      []interface{}{"a", "b"},
+		"server": map[string]interface{}{
+			"host":       map[string]interface{}{"name": "h"},
+			"quoted key": "line one\nline two",
+			"raw":        "it's raw\\n",
+			"sub":        map[string]interface{}{"x": "é😀"},
+		},
+	}
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("ReadTOML() =\n%#v\nwant\n%#v", tree, want)
+	}
+
+	v, err := FromTree(tree, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.Get("server.host.name"); got != "h" {
+		t.Errorf("server.host.name = %q; want h", got)
+	}
+	if got := v.Get("inf"); got != "-inf" {
+		t.Errorf("inf = %q; want -inf", got)
+	}
+}
+
+func TestReadTOML_errors(t *testing.T) {
+	cases := []struct {
+		src       string
+		line, col int
+	}{
+		{"a = 1\na = 2", 2, 6},
+		{"[t]\n[t]", 2, 4},
+		{"[[t]]", 1, 2},
+		{"a = {b = 1}", 1, 5},
+		{"a = \"open", 1, 10},
+		{"a = 1 2", 1, 7},
+		{"a = bogus", 1, 5},
+		{"a = [1, 2", 1, 10},
+		{"= 1", 1, 1},
+		{"a = \"\\q\"", 1, 7},
+		{"a = 010", 1, 5},
+		{"a = 0X1F", 1, 5},
+		{"a = _1", 1, 5},
+		{"a = 1__0", 1, 5},
+		{"a = 1_", 1, 5},
+		{"a = +0x1", 1, 5},
+		{"a = 0x", 1, 5},
+		{"a = 0o8", 1, 5},
+		{"a = 01.5", 1, 5},
+		{"a = 1.", 1, 5},
+		{"a = .5", 1, 5},
+		{"a = 1e", 1, 5},
+	}
+	for _, c := range cases {
+		_, err := ReadTOML(strings.NewReader(c.src))
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("ReadTOML(%q) = %v; want *SyntaxError", c.src, err)
+			continue
+		}
+		if se.Line != c.line || se.Col != c.col {
+			t.Errorf("ReadTOML(%q) = %v; want error at %d:%d", c.src, err, c.line, c.col)
+		}
+	}
+}
+
+func TestReadTOML_numbers(t *testing.T) {
+	cases := map[string]interface{}{
+		"0":           int64(0),
+		"+0":          int64(0),
+		"-17":         int64(-17),
+		"1_000_000":   int64(1000000),
+		"0xDEAD_beef": int64(0xdeadbeef),
+		"0o755":       int64(0o755),
+		"0b1101":      int64(13),
+		"0.5":         0.5,
+		"-0.0":        math.Copysign(0, -1),
+		"1e06":        1e6,
+		"5E+2_2":      5e22,
+		"6.626e-34":   6.626e-34,
+		"9_224.6_17":  9224.617,
+	}
+	for raw, want := range cases {
+		tree, err := ReadTOML(strings.NewReader("n = " + raw))
+		if err != nil {
+			t.Errorf("ReadTOML(%q) = %v", raw, err)
+		} else if got := tree["n"]; !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadTOML(%q) = %#v; want %#v", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package ini
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ToTree converts v to a tree of nested maps, splitting keys into sections on sep. If sep is None,
+// keys are not split, and if sep is the empty string, it defaults to "." (period). Keys with a
+// single value are strings in the tree, and keys with any other number of values are []string.
+//
+// If a key is also the name of a section, as with "a" and "a.b", its values are stored under the
+// empty key of the section's map: {"a": {"": "1", "b": "2"}}. ToTree returns an error if two keys
+// have the same place in the tree, as with "a" and "a." (whose last segment is empty).
+//
+// The tree is suitable for encoding in formats such as JSON, TOML, or YAML. To convert a Document,
+// convert its Values.
+func ToTree(v Values, sep string) (map[string]interface{}, error) {
+	sep = separatorOrDefault(sep)
+	tree := make(map[string]interface{})
+	for _, k := range v.sortedKeys() {
+		var value interface{}
+		if vs := v[k]; len(vs) == 1 {
+			value = vs[0]
+		} else {
+			value = append(make([]string, 0, len(vs)), vs...)
+		}
+		if !setTree(tree, splitKey(k, sep), value) {
+			return nil, fmt.Errorf("ini: key %q has the same place in a tree as another key", k)
+		}
+	}
+	return tree, nil
+}
+
+// setTree sets the value at path in tree, creating maps for each element of path but the last. If
+// a value and a map share a path, the value is stored under the empty key of the map. setTree
+// returns false if another value is already stored at path, in which case tree may be partly
+// modified.
+func setTree(tree map[string]interface{}, path []string, value interface{}) bool {
+	for _, name := range path[:len(path)-1] {
+		switch sub := tree[name].(type) {
+		case map[string]interface{}:
+			tree = sub
+		case nil:
+			next := make(map[string]interface{})
+			tree[name] = next
+			tree = next
+		default:
+			if name == "" {
+				// The value belongs to the map holding it, not to a map of its own
+				return false
+			}
+			next := map[string]interface{}{"": sub}
+			tree[name] = next
+			tree = next
+		}
+	}
+
+	name := path[len(path)-1]
+	if sub, ok := tree[name].(map[string]interface{}); ok {
+		tree, name = sub, ""
+	}
+	if _, ok := tree[name]; ok {
+		return false
+	}
+	tree[name] = value
+	return true
+}
+
+// FromTree converts a tree of nested maps to Values, joining the names of maps and their keys with
+// sep. If sep is None, names are joined without a separator, and if sep is the empty string, it
+// defaults to "." (period).
+//
+// Strings, booleans, and numbers become single values, and slices of them become multiple values.
+// A nil value or empty slice becomes a key with no values. As the inverse of ToTree, a value under
+// the empty key of a map is given the map's own key. If more than one path in the tree yields the
+// same key, as with {"a.b": "1", "a": {"b": "2"}}, the key's values are ordered by the sorted names
+// of each map along their paths. FromTree returns an error if the tree contains any other type,
+// including slices of maps, and reports the first such value in the same order.
+func FromTree(tree map[string]interface{}, sep string) (Values, error) {
+	v := make(Values)
+	if err := flattenTree(v, "", tree, separatorOrDefault(sep)); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// flattenTree adds the members of tree to v, prefixing their keys with prefix. Members are added in
+// sorted order, so that values of keys that share a path are in the same order each time.
+func flattenTree(v Values, prefix string, tree map[string]interface{}, sep string) error {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := tree[name]
+		key := prefix + name
+		if name == "" && prefix != "" {
+			key = strings.TrimSuffix(prefix, sep)
+		}
+
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if err := flattenTree(v, key+sep, value, sep); err != nil {
+				return err
+			}
+		case []string:
+			v[key] = append(v[key], value...)
+		case []interface{}:
+			values := make([]string, 0, len(value))
+			for _, elem := range value {
+				s, err := treeString(key, elem)
+				if err != nil {
+					return err
+				}
+				values = append(values, s)
+			}
+			v[key] = append(v[key], values...)
+		case nil:
+			if _, ok := v[key]; !ok {
+				v[key] = nil
+			}
+		default:
+			s, err := treeString(key, value)
+			if err != nil {
+				return err
+			}
+			v.Add(key, s)
+		}
+	}
+	return nil
+}
+
+// treeString returns a scalar value of a tree as a string.
+func treeString(key string, value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case json.Number:
+		return value.String(), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case uint64:
+		return strconv.FormatUint(value, 10), nil
+	case float64:
+		return formatFloat(value), nil
+	case fmt.Stringer:
+		return value.String(), nil
+	default:
+		return "", fmt.Errorf("ini: cannot convert %T to a value of %q", value, key)
+	}
+}
+
+// formatFloat returns f formatted as briefly as possible.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// sortedTreeKeys returns the keys of tree in sorted order.
+func sortedTreeKeys(tree map[string]interface{}) []string {
+	keys := make([]string, 0, len(tree))
+	for k := range tree {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
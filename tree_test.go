@@ -0,0 +1,130 @@
+package ini
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testTree(t *testing.T, v Values, sep string) map[string]interface{} {
+	t.Helper()
+	tree, err := ToTree(v, sep)
+	if err != nil {
+		t.Fatalf("ToTree(%q) = %v", sep, err)
+	}
+	return tree
+}
+
+func TestToTree(t *testing.T) {
+	v := Values{"a": {"1"}, "a.b": {"2", "3"}, "c": nil, "s/t": {"x"}}
+	want := map[string]interface{}{
+		"a":   map[string]interface{}{"": "1", "b": []string{"2", "3"}},
+		"c":   []string{},
+		"s/t": "x",
+	}
+	tree := testTree(t, v, "")
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("ToTree() = %#v; want %#v", tree, want)
+	}
+
+	back, err := FromTree(tree, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, v) {
+		t.Errorf("FromTree(ToTree()) = %v; want %v", back, v)
+	}
+
+	want = map[string]interface{}{"a": "1", "a.b": []string{"2", "3"}, "c": []string{}, "s": map[string]interface{}{"t": "x"}}
+	if tree := testTree(t, v, "/"); !reflect.DeepEqual(tree, want) {
+		t.Errorf("ToTree(/) = %#v; want %#v", tree, want)
+	}
+}
+
+func TestToTree_collisions(t *testing.T) {
+	for _, v := range []Values{
+		{"a": {"1"}, "a.": {"2"}},
+		{"a.": {"1"}, "a.b": {"2"}, "a": {"3"}},
+		{"a": {"1"}, "a..b": {"2"}},
+		{"a.": {"1"}, "a..": {"2"}},
+	} {
+		if tree, err := ToTree(v, ""); err == nil {
+			t.Errorf("ToTree(%q) = %#v; want error", v, tree)
+		}
+		if got, err := ToJSON(v, JSONOptions{}); err == nil {
+			t.Errorf("ToJSON(%q) = %s; want error", v, got)
+		}
+	}
+}
+
+func TestFromTree(t *testing.T) {
+	tree := map[string]interface{}{
+		"s": map[string]interface{}{
+			"b":   true,
+			"i":   42,
+			"i64": int64(-7),
+			"f":   1.5,
+			"n":   json.Number("1e3"),
+			"arr": []interface{}{"x", int64(1), false},
+			"nil": nil,
+		},
+	}
+	want := Values{
+		"s.b":   {"true"},
+		"s.i":   {"42"},
+		"s.i64": {"-7"},
+		"s.f":   {"1.5"},
+		"s.n":   {"1e3"},
+		"s.arr": {"x", "1", "false"},
+		"s.nil": nil,
+	}
+	got, err := FromTree(tree, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromTree() = %v; want %v", got, want)
+	}
+
+	if got, err := FromTree(map[string]interface{}{"s": map[string]interface{}{"k": "v"}}, None); err != nil {
+		t.Fatal(err)
+	} else if want := (Values{"sk": {"v"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("FromTree(None) = %v; want %v", got, want)
+	}
+
+	for _, bad := range []interface{}{
+		[]interface{}{map[string]interface{}{}},
+		[]interface{}{[]interface{}{}},
+		struct{}{},
+	} {
+		if _, err := FromTree(map[string]interface{}{"k": bad}, ""); err == nil {
+			t.Errorf("FromTree(%#v) = nil; want error", bad)
+		}
+	}
+}
+
+func TestFromTree_order(t *testing.T) {
+	tree := map[string]interface{}{
+		"a.b": "1",
+		"a":   map[string]interface{}{"b": "2", "c": "3"},
+		"z":   map[string]interface{}{"": "4"},
+		"z.":  "5",
+	}
+	bad := map[string]interface{}{"a": struct{}{}, "b": struct{}{}, "c": struct{}{}}
+
+	// Map iteration order varies, so check that the results do not
+	for i := 0; i < 20; i++ {
+		got, err := FromTree(tree, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (Values{"a.b": {"2", "1"}, "a.c": {"3"}, "z": {"4"}, "z.": {"5"}}); !reflect.DeepEqual(got, want) {
+			t.Fatalf("FromTree() = %v; want %v", got, want)
+		}
+
+		_, err = FromTree(bad, "")
+		if want := `ini: cannot convert struct {} to a value of "a"`; err == nil || err.Error() != want {
+			t.Fatalf("FromTree(bad) = %v; want %s", err, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package ini
 
 import (
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -90,3 +91,46 @@ func TestValues_matching(t *testing.T) {
 		"foo.baz": []string{"y", "x"},
 	})
 }
+
+func TestValues_iterators(t *testing.T) {
+	v := Values{
+		"b.x":   []string{"1", "2"},
+		"a.y.z": []string{"3"},
+		"a.y.w": nil,
+		"top":   []string{"4"},
+	}
+
+	var all []string
+	for k, value := range v.All() {
+		all = append(all, k+"="+value)
+	}
+	if want := []string{"a.y.z=3", "b.x=1", "b.x=2", "top=4"}; !reflect.DeepEqual(all, want) {
+		t.Errorf("All() = %q; want %q", all, want)
+	}
+
+	if got, want := slices.Collect(v.Keys()), []string{"a.y.w", "a.y.z", "b.x", "top"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %q; want %q", got, want)
+	}
+
+	if got, want := slices.Collect(v.Sections("")), []string{"a.y", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sections(\"\") = %q; want %q", got, want)
+	}
+	sv := Values{"a:b.c": {"1"}, "a:d": {"2"}, "e": {"3"}}
+	if got, want := slices.Collect(sv.Sections(":")), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sections(\":\") = %q; want %q", got, want)
+	}
+	if got := slices.Collect(sv.Sections(None)); len(got) != 0 {
+		t.Errorf("Sections(None) = %q; want none", got)
+	}
+
+	// Stopping early
+	for range v.All() {
+		break
+	}
+	for range v.Keys() {
+		break
+	}
+	for range v.Sections("") {
+		break
+	}
+}